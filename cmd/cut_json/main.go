@@ -1,12 +1,15 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/ALONELUR/cut_json/cutjson"
@@ -19,65 +22,165 @@ func main() {
 		paths          string
 		keepIfValue    string
 		keepArrayMatch string
+		drops          string
+		dropArrayMatch string
+		arrayFields    string
+		fields         string
+		fieldMask      string
+		masks          string
+		maskSalt       string
 		configPath     string
 		prettyOut      bool
+		arrayMode      string
+		placeholder    string
+		conflict       string
+		keepAll        bool
+		sortKeys       bool
+		stream         bool
+		ndjson         bool
+		invalidLines   string
 	)
 
 	flag.StringVar(&filePath, "file", "", "JSON文件路径 (如果不提供，则从标准输入读取)")
 	flag.StringVar(&paths, "path", "", "规则1: 要保留的路径，多个路径用逗号分隔")
-	flag.StringVar(&keepIfValue, "keep-if-value", "", "规则2: 格式为'路径=值'，如果指定路径的值等于配置值，则保留父路径")
-	flag.StringVar(&keepArrayMatch, "keep-array-match", "", "规则3: 格式为'数组路径:子路径=值'，保留数组中满足子路径值为配置值的元素")
+	flag.StringVar(&keepIfValue, "keep-if-value", "", "规则2: 格式为'路径=值'，如果指定路径的值等于配置值，则保留父路径；也支持!=、>、>=、<、<=和正则匹配~=")
+	flag.StringVar(&keepArrayMatch, "keep-array-match", "", "规则3: 格式为'数组路径:子路径=值'，保留数组中满足子路径值为配置值的元素；也支持!=、>、>=、<、<=和正则匹配~=")
+	flag.StringVar(&drops, "drop", "", "规则4: 要移除的路径，多个路径用逗号分隔；没有保留规则时从完整文档中移除")
+	flag.StringVar(&arrayFields, "array-fields", "", "规则3中匹配元素要保留的子路径，多个路径用逗号分隔；为空时保留整个元素")
+	flag.StringVar(&fields, "fields", "", "部分响应字段选择器，如 user(name,address/city),orders/items/productId")
+	flag.StringVar(&fieldMask, "field-mask", "", "protobuf FieldMask路径，多个路径用逗号分隔，如 user.display_name,products.id")
+	flag.StringVar(&dropArrayMatch, "drop-array-match", "", "规则6: 格式同-keep-array-match，移除数组中满足条件的元素")
+	flag.StringVar(&masks, "mask", "", "规则5: 要替换的路径，格式为'路径'或'路径=方式'，方式可以是fixed[:文本]、last:N、hash或null，多个路径用逗号分隔")
+	flag.StringVar(&maskSalt, "mask-salt", "", "hash替换方式使用的盐")
 	flag.StringVar(&configPath, "config", "", "JSON配置文件路径，用于从配置文件加载规则")
 	flag.BoolVar(&prettyOut, "pretty", false, "是否美化输出的JSON")
+	flag.StringVar(&arrayMode, "array-mode", "compact", "数组输出方式: compact（只输出保留的元素）或 preserve（保持元素原有位置）")
+	flag.StringVar(&placeholder, "placeholder", "null", "preserve模式下未保留数组位置的占位值（JSON格式）")
+	flag.StringVar(&conflict, "conflict", "union", "多个规则保留重叠内容时的合并策略: union、last-wins、first-wins 或 intersection")
+	flag.BoolVar(&keepAll, "keep-all", false, "从完整文档开始裁剪，只由-drop等移除规则删除内容")
+	flag.BoolVar(&sortKeys, "sort-keys", false, "输出对象的键按字母顺序排列，默认保持源文档中的顺序")
+	flag.BoolVar(&stream, "stream", false, "流式处理输入，不把整个文档读入内存，适合很大的文件（不能与-pretty和-sort-keys同时使用）")
+	flag.BoolVar(&ndjson, "ndjson", false, "输入为NDJSON（每行一个JSON记录），逐行独立裁剪，每条记录输出一行结果（不能与-pretty和-stream同时使用）")
+	flag.StringVar(&invalidLines, "invalid-lines", "abort", "-ndjson模式下遇到无效JSON行时的处理方式: abort（停止并报错）、skip（跳过）或 null（输出null）")
 	flag.Parse()
 
+	// 记录命令行中显式指定的参数
+	setFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+
 	// 检查是否提供了至少一个规则或配置文件
-	if paths == "" && keepIfValue == "" && keepArrayMatch == "" && configPath == "" {
+	if paths == "" && keepIfValue == "" && keepArrayMatch == "" && drops == "" && dropArrayMatch == "" && masks == "" && fields == "" && fieldMask == "" && configPath == "" && !keepAll {
 		fmt.Println("错误: 必须提供至少一个规则参数或配置文件")
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	// 读取JSON数据
-	var jsonData []byte
-	var err error
+	if stream && (prettyOut || sortKeys) {
+		log.Fatalf("-stream不能与-pretty或-sort-keys同时使用")
+	}
 
+	if ndjson && (prettyOut || stream) {
+		log.Fatalf("-ndjson不能与-pretty或-stream同时使用")
+	}
+
+	// 打开JSON输入，如果没有提供文件则从标准输入读取
+	input := io.Reader(os.Stdin)
 	if filePath != "" {
-		// 从文件读取
-		jsonData, err = os.ReadFile(filePath)
+		file, err := os.Open(filePath)
 		if err != nil {
 			log.Fatalf("无法读取文件 %s: %v", filePath, err)
 		}
-	} else {
-		// 从标准输入读取
-		jsonData, err = io.ReadAll(os.Stdin)
-		if err != nil {
-			log.Fatalf("无法从标准输入读取: %v", err)
-		}
+		defer file.Close()
+		input = file
 	}
 
 	// 构建规则列表
 	var rules []cutjson.Rule
+	var opts cutjson.Options
+	var err error
 
 	if configPath != "" {
-		// 从配置文件加载规则
-		rules, err = cutjson.LoadRulesFromConfig(configPath)
+		// 从配置文件加载规则和选项
+		rules, opts, err = cutjson.LoadConfig(configPath)
 		if err != nil {
 			log.Fatalf("从配置文件加载规则时出错: %v", err)
 		}
 
 		// 如果同时提供了命令行规则，则合并规则
-		if paths != "" || keepIfValue != "" || keepArrayMatch != "" {
-			cmdRules := buildRules(paths, keepIfValue, keepArrayMatch)
+		if paths != "" || keepIfValue != "" || keepArrayMatch != "" || drops != "" || dropArrayMatch != "" || masks != "" {
+			cmdRules := buildRules(paths, keepIfValue, keepArrayMatch, arrayFields, drops, dropArrayMatch, masks, maskSalt)
 			rules = append(rules, cmdRules...)
 		}
 	} else {
 		// 仅使用命令行规则
-		rules = buildRules(paths, keepIfValue, keepArrayMatch)
+		rules = buildRules(paths, keepIfValue, keepArrayMatch, arrayFields, drops, dropArrayMatch, masks, maskSalt)
+	}
+
+	// 处理字段选择器
+	if fields != "" {
+		fieldRules, err := cutjson.ParseFieldSelector(fields)
+		if err != nil {
+			log.Fatalf("无效的字段选择器 %s: %v", fields, err)
+		}
+		rules = append(rules, fieldRules...)
+	}
+
+	// 处理FieldMask
+	if fieldMask != "" {
+		mask, err := cutjson.ParseFieldMask(fieldMask)
+		if err != nil {
+			log.Fatalf("无效的FieldMask %s: %v", fieldMask, err)
+		}
+		maskRules, err := mask.Rules()
+		if err != nil {
+			log.Fatalf("无效的FieldMask %s: %v", fieldMask, err)
+		}
+		rules = append(rules, maskRules...)
+	}
+
+	// 命令行中显式指定的选项覆盖配置文件中的选项
+	if err := applyOptionFlags(&opts, setFlags, arrayMode, placeholder, conflict, invalidLines, keepAll); err != nil {
+		log.Fatalf("无效的选项: %v", err)
+	}
+
+	// 命令行工具默认保持源文档中键的顺序，-sort-keys和配置文件中的preserve_key_order可以改变这一点
+	if setFlags["sort-keys"] {
+		opts.PreserveKeyOrder = !sortKeys
+	} else if configPath == "" || !configSetsKeyOrder(configPath) {
+		opts.PreserveKeyOrder = true
+	}
+
+	// NDJSON输入逐行独立裁剪，规则只编译一次
+	if ndjson {
+		cutter, err := cutjson.CompileWithOptions(rules, opts)
+		if err != nil {
+			log.Fatalf("无效的规则: %v", err)
+		}
+		if err := cutjson.CutStream(input, os.Stdout, cutter); err != nil {
+			log.Fatalf("应用规则时出错: %v", err)
+		}
+		return
+	}
+
+	// 流式处理时不把整个文档读入内存，结果直接写到标准输出
+	if stream {
+		if err := cutjson.CutReader(input, os.Stdout, rules, opts); err != nil {
+			log.Fatalf("应用规则时出错: %v", err)
+		}
+		fmt.Println()
+		return
+	}
+
+	// 读取JSON数据
+	jsonData, err := io.ReadAll(input)
+	if err != nil {
+		log.Fatalf("无法读取JSON输入: %v", err)
 	}
 
 	// 应用规则
-	result, err := cutjson.CutWithRules(jsonData, rules)
+	result, err := cutjson.CutWithOptions(jsonData, rules, opts)
 	if err != nil {
 		log.Fatalf("应用规则时出错: %v", err)
 	}
@@ -87,12 +190,12 @@ func main() {
 }
 
 // buildRules 根据命令行参数构建规则列表
-func buildRules(paths, keepIfValue, keepArrayMatch string) []cutjson.Rule {
+func buildRules(paths, keepIfValue, keepArrayMatch, arrayFields, drops, dropArrayMatch, masks, maskSalt string) []cutjson.Rule {
 	rules := []cutjson.Rule{}
 
 	// 处理规则1: 保留指定路径
 	if paths != "" {
-		pathList := strings.Split(paths, ",")
+		pathList := splitOutsideQuotes(paths, ',', -1)
 		for _, path := range pathList {
 			path = strings.TrimSpace(path)
 			if path != "" {
@@ -103,76 +206,298 @@ func buildRules(paths, keepIfValue, keepArrayMatch string) []cutjson.Rule {
 
 	// 处理规则2: 如果值匹配，保留父路径
 	if keepIfValue != "" {
-		pairs := strings.Split(keepIfValue, ",")
+		pairs := splitOutsideQuotes(keepIfValue, ',', -1)
 		for _, pair := range pairs {
 			pair = strings.TrimSpace(pair)
 			if pair == "" {
 				continue
 			}
 
-			parts := strings.SplitN(pair, "=", 2)
-			if len(parts) != 2 {
+			path, op, value, ok := splitCondition(pair)
+			if !ok {
 				log.Printf("警告: 忽略无效的规则2格式: %s", pair)
 				continue
 			}
 
-			path := strings.TrimSpace(parts[0])
-			value := strings.TrimSpace(parts[1])
-
-			// 尝试将值解析为JSON
-			var parsedValue interface{}
-			if err := json.Unmarshal([]byte(value), &parsedValue); err != nil {
-				// 如果不是有效的JSON，则视为字符串
+			// 尝试将值解析为JSON，正则表达式始终作为字符串
+			parsedValue := parseValue(value)
+			if _, ok := parsedValue.(string); op == cutjson.OpRegex && !ok {
 				parsedValue = value
 			}
 
-			rules = append(rules, cutjson.NewKeepParentIfValueMatchesRule(path, parsedValue))
+			rules = append(rules, cutjson.NewKeepParentIfValueComparesRule(path, op, parsedValue))
 		}
 	}
 
 	// 处理规则3: 保留数组中满足条件的元素
 	if keepArrayMatch != "" {
-		pairs := strings.Split(keepArrayMatch, ",")
-		for _, pair := range pairs {
-			pair = strings.TrimSpace(pair)
-			if pair == "" {
-				continue
+		var fields []string
+		for _, field := range splitOutsideQuotes(arrayFields, ',', -1) {
+			if field = strings.TrimSpace(field); field != "" {
+				fields = append(fields, field)
+			}
+		}
+
+		for _, rule := range buildArrayMatchRules(keepArrayMatch, "规则3", cutjson.NewKeepArrayElementsIfChildValueComparesRule) {
+			rule.Fields = fields
+			rules = append(rules, rule)
+		}
+	}
+
+	// 处理规则4: 移除指定路径
+	if drops != "" {
+		for _, path := range splitOutsideQuotes(drops, ',', -1) {
+			path = strings.TrimSpace(path)
+			if path != "" {
+				rules = append(rules, cutjson.NewDropPathRule(path))
 			}
+		}
+	}
 
-			// 分割数组路径和条件
-			pathParts := strings.SplitN(pair, ":", 2)
-			if len(pathParts) != 2 {
-				log.Printf("警告: 忽略无效的规则3格式: %s", pair)
+	// 处理规则5: 替换指定路径的值
+	if masks != "" {
+		for _, entry := range splitOutsideQuotes(masks, ',', -1) {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
 				continue
 			}
 
-			arrayPath := strings.TrimSpace(pathParts[0])
-			condition := strings.TrimSpace(pathParts[1])
+			parts := splitOutsideQuotes(entry, '=', 2)
+			spec := ""
+			if len(parts) == 2 {
+				spec = strings.TrimSpace(parts[1])
+			}
 
-			// 分割子路径和值
-			condParts := strings.SplitN(condition, "=", 2)
-			if len(condParts) != 2 {
-				log.Printf("警告: 忽略无效的规则3条件格式: %s", condition)
+			mask, err := parseMaskSpec(spec, maskSalt)
+			if err != nil {
+				log.Printf("警告: 忽略无效的规则5格式: %s (%v)", entry, err)
 				continue
 			}
 
-			childPath := strings.TrimSpace(condParts[0])
-			value := strings.TrimSpace(condParts[1])
+			rules = append(rules, cutjson.NewMaskPathRule(strings.TrimSpace(parts[0]), mask))
+		}
+	}
 
-			// 尝试将值解析为JSON
-			var parsedValue interface{}
-			if err := json.Unmarshal([]byte(value), &parsedValue); err != nil {
-				// 如果不是有效的JSON，则视为字符串
-				parsedValue = value
-			}
+	// 处理规则6: 移除数组中满足条件的元素
+	if dropArrayMatch != "" {
+		rules = append(rules, buildArrayMatchRules(dropArrayMatch, "规则6", cutjson.NewDropArrayElementsIfChildValueComparesRule)...)
+	}
+
+	return rules
+}
+
+// buildArrayMatchRules 解析'数组路径:子路径<操作符>值'格式的条件列表，使用newRule构建规则3或规则6
+func buildArrayMatchRules(spec, name string, newRule func(string, string, cutjson.Operator, interface{}) cutjson.Rule) []cutjson.Rule {
+	var rules []cutjson.Rule
 
-			rules = append(rules, cutjson.NewKeepArrayElementsIfChildValueMatchesRule(arrayPath, childPath, parsedValue))
+	pairs := splitOutsideQuotes(spec, ',', -1)
+	for _, pair := range pairs {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
 		}
+
+		// 分割数组路径和条件
+		pathParts := splitOutsideQuotes(pair, ':', 2)
+		if len(pathParts) != 2 {
+			log.Printf("警告: 忽略无效的%s格式: %s", name, pair)
+			continue
+		}
+
+		arrayPath := strings.TrimSpace(pathParts[0])
+		condition := strings.TrimSpace(pathParts[1])
+
+		// 分割子路径、操作符和值
+		childPath, op, value, ok := splitCondition(condition)
+		if !ok {
+			log.Printf("警告: 忽略无效的%s条件格式: %s", name, condition)
+			continue
+		}
+
+		// 尝试将值解析为JSON，正则表达式始终作为字符串
+		parsedValue := parseValue(value)
+		if _, ok := parsedValue.(string); op == cutjson.OpRegex && !ok {
+			parsedValue = value
+		}
+
+		rules = append(rules, newRule(arrayPath, childPath, op, parsedValue))
 	}
 
 	return rules
 }
 
+// parseMaskSpec 解析命令行中的替换方式: fixed[:文本]、last:N、hash 或 null，为空时使用fixed
+func parseMaskSpec(spec, salt string) (cutjson.Mask, error) {
+	name, arg, hasArg := strings.Cut(spec, ":")
+
+	switch name {
+	case "", "fixed":
+		return cutjson.Mask{Mode: cutjson.MaskFixed, Text: arg}, nil
+	case "last":
+		n, err := strconv.Atoi(arg)
+		if !hasArg || err != nil || n <= 0 {
+			return cutjson.Mask{}, errors.New("last替换方式需要一个正整数，如last:4")
+		}
+		return cutjson.Mask{Mode: cutjson.MaskKeepLast, KeepLast: n}, nil
+	case "hash":
+		return cutjson.Mask{Mode: cutjson.MaskHash, Salt: salt}, nil
+	case "null":
+		return cutjson.Mask{Mode: cutjson.MaskNull}, nil
+	default:
+		return cutjson.Mask{}, fmt.Errorf("未知的替换方式: %s", name)
+	}
+}
+
+// applyOptionFlags 将命令行中显式指定的选项写入opts
+func applyOptionFlags(opts *cutjson.Options, setFlags map[string]bool, arrayMode, placeholder, conflict, invalidLines string, keepAll bool) error {
+	if setFlags["array-mode"] {
+		mode, err := cutjson.ParseArrayMode(arrayMode)
+		if err != nil {
+			return err
+		}
+		opts.ArrayMode = mode
+	}
+
+	if setFlags["placeholder"] {
+		opts.Placeholder = parseValue(placeholder)
+	}
+
+	if setFlags["conflict"] {
+		policy, err := cutjson.ParseConflictPolicy(conflict)
+		if err != nil {
+			return err
+		}
+		opts.Conflict = policy
+	}
+
+	if setFlags["keep-all"] {
+		opts.KeepAll = keepAll
+	}
+
+	if setFlags["invalid-lines"] {
+		policy, err := cutjson.ParseInvalidLinePolicy(invalidLines)
+		if err != nil {
+			return err
+		}
+		opts.InvalidLines = policy
+	}
+
+	return nil
+}
+
+// configSetsKeyOrder 判断配置文件的选项中是否指定了preserve_key_order
+func configSetsKeyOrder(configPath string) bool {
+	configData, err := os.ReadFile(configPath)
+	if err != nil {
+		return false
+	}
+
+	var config struct {
+		Options struct {
+			PreserveKeyOrder *bool `json:"preserve_key_order"`
+		} `json:"options"`
+	}
+	if err := json.Unmarshal(configData, &config); err != nil {
+		return false
+	}
+	return config.Options.PreserveKeyOrder != nil
+}
+
+// splitOutsideQuotes 按分隔符拆分字符串，忽略引号和方括号内以及被反斜杠转义的分隔符，
+// 使路径中带点或逗号的键（如 a["x,y"]）不会被拆开；n小于0时不限制拆分的段数
+func splitOutsideQuotes(s string, sep byte, n int) []string {
+	var parts []string
+	var quote byte
+	depth := 0
+	start := 0
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']' && depth > 0:
+			depth--
+		case c == sep && depth == 0 && (n < 0 || len(parts) < n-1):
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+// conditionOperators 命令行条件中支持的操作符，较长的符号排在前面以便优先匹配
+var conditionOperators = []struct {
+	symbol string
+	op     cutjson.Operator
+}{
+	{"~=", cutjson.OpRegex},
+	{"!=", cutjson.OpNotEquals},
+	{">=", cutjson.OpGreaterOrEqual},
+	{"<=", cutjson.OpLessOrEqual},
+	{"=", cutjson.OpEquals},
+	{">", cutjson.OpGreaterThan},
+	{"<", cutjson.OpLessThan},
+}
+
+// splitCondition 在引号和方括号之外查找第一个比较操作符，把'路径<操作符>值'拆分为三部分
+func splitCondition(s string) (string, cutjson.Operator, string, bool) {
+	var quote byte
+	depth := 0
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']' && depth > 0:
+			depth--
+		case depth == 0:
+			for _, o := range conditionOperators {
+				if strings.HasPrefix(s[i:], o.symbol) {
+					path := strings.TrimSpace(s[:i])
+					value := strings.TrimSpace(s[i+len(o.symbol):])
+					return path, o.op, value, true
+				}
+			}
+		}
+	}
+
+	return "", 0, "", false
+}
+
+// parseValue 尝试将值解析为JSON，如果不是有效的JSON，则视为字符串；数字保留为json.Number以免丢失精度
+func parseValue(value string) interface{} {
+	dec := json.NewDecoder(strings.NewReader(value))
+	dec.UseNumber()
+
+	var parsedValue interface{}
+	if err := dec.Decode(&parsedValue); err != nil {
+		return value
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return value
+	}
+	return parsedValue
+}
+
 // printJSON 根据是否需要美化输出JSON
 func printJSON(data []byte, pretty bool) {
 	if !pretty {
@@ -180,20 +505,13 @@ func printJSON(data []byte, pretty bool) {
 		return
 	}
 
-	// 美化JSON输出
-	var obj interface{}
-	if err := json.Unmarshal(data, &obj); err != nil {
-		// 如果无法解析为JSON对象，直接输出原始内容
-		fmt.Println(string(data))
-		return
-	}
-
-	prettyJSON, err := json.MarshalIndent(obj, "", "  ")
-	if err != nil {
+	// 美化JSON输出，直接缩进原始内容以保持键的顺序
+	var prettyJSON bytes.Buffer
+	if err := json.Indent(&prettyJSON, data, "", "  "); err != nil {
 		// 如果美化失败，输出原始内容
 		fmt.Println(string(data))
 		return
 	}
 
-	fmt.Println(string(prettyJSON))
+	fmt.Println(prettyJSON.String())
 }
@@ -0,0 +1,555 @@
+package cutjson
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Operator defines how the values found in the document are compared with the configured value
+type Operator int
+
+const (
+	// OpEquals 等于配置值
+	OpEquals Operator = iota
+	// OpNotEquals 不等于配置值
+	OpNotEquals
+	// OpGreaterThan 大于配置值
+	OpGreaterThan
+	// OpGreaterOrEqual 大于或等于配置值
+	OpGreaterOrEqual
+	// OpLessThan 小于配置值
+	OpLessThan
+	// OpLessOrEqual 小于或等于配置值
+	OpLessOrEqual
+	// OpIn 等于配置数组中的某个值
+	OpIn
+	// OpNotIn 不等于配置数组中的任何值
+	OpNotIn
+	// OpRegex 字符串匹配配置的正则表达式
+	OpRegex
+	// OpStartsWith 字符串以配置值开头
+	OpStartsWith
+	// OpEndsWith 字符串以配置值结尾
+	OpEndsWith
+	// OpContains 字符串包含配置值
+	OpContains
+	// OpGlob 字符串匹配配置的通配符模式（*、?和[...]）
+	OpGlob
+	// OpExists 路径存在（值可以是null）
+	OpExists
+	// OpNotExists 路径不存在
+	OpNotExists
+	// OpIsNull 值为null
+	OpIsNull
+	// OpIsType 值的类型为配置的类型: string、number、bool、object 或 array
+	OpIsType
+	// OpNonEmpty 值存在且不是null、空字符串、空数组或空对象
+	OpNonEmpty
+)
+
+// Condition is a boolean expression evaluated against a context node: the value kept by a
+// KeepParentIfValueMatches rule or each element of a KeepArrayElementsIfChildValueMatches rule.
+// Exactly one of All, Any and Not may be set; otherwise the condition is a leaf comparing the
+// values at Path, relative to the context node (empty for the node itself), using Op and Value
+type Condition struct {
+	All        []Condition // 所有子条件都满足
+	Any        []Condition // 任一子条件满足
+	Not        *Condition  // 子条件不满足
+	Path       string      // 叶子条件: 相对于上下文节点的路径
+	Op         Operator    // 叶子条件: 比较操作符
+	Value      interface{} // 叶子条件: 配置值
+	IgnoreCase bool        // 叶子条件: 字符串匹配是否忽略大小写
+}
+
+// All creates a condition that holds when every one of conditions holds
+func All(conditions ...Condition) Condition {
+	return Condition{All: conditions}
+}
+
+// Any creates a condition that holds when at least one of conditions holds
+func Any(conditions ...Condition) Condition {
+	return Condition{Any: conditions}
+}
+
+// Not creates a condition that holds when condition does not
+func Not(condition Condition) Condition {
+	return Condition{Not: &condition}
+}
+
+// Where creates a leaf condition comparing the values at path with value
+func Where(path string, op Operator, value interface{}) Condition {
+	return Condition{Path: path, Op: op, Value: value}
+}
+
+// condition is a compiled Condition with parsed paths and comparisons
+type condition struct {
+	all      []*condition
+	any      []*condition
+	not      *condition
+	segments []segment
+	cmp      *comparison
+}
+
+// compileCondition parses the paths and builds the comparisons of a condition tree
+func compileCondition(c Condition, syntax PathSyntax) (*condition, error) {
+	composites := 0
+	if c.All != nil {
+		composites++
+	}
+	if c.Any != nil {
+		composites++
+	}
+	if c.Not != nil {
+		composites++
+	}
+
+	switch {
+	case composites > 1:
+		return nil, ErrInvalidRule
+
+	case c.All != nil || c.Any != nil:
+		children := c.All
+		if c.Any != nil {
+			children = c.Any
+		}
+		compiled := make([]*condition, 0, len(children))
+		for _, child := range children {
+			cc, err := compileCondition(child, syntax)
+			if err != nil {
+				return nil, err
+			}
+			compiled = append(compiled, cc)
+		}
+		if c.All != nil {
+			return &condition{all: compiled}, nil
+		}
+		return &condition{any: compiled}, nil
+
+	case c.Not != nil:
+		inner, err := compileCondition(*c.Not, syntax)
+		if err != nil {
+			return nil, err
+		}
+		return &condition{not: inner}, nil
+	}
+
+	cmp, err := newComparison(c.Op, c.Value, c.IgnoreCase)
+	if err != nil {
+		return nil, err
+	}
+
+	leaf := &condition{cmp: cmp}
+	if c.Path != "" {
+		segments, err := parseRulePath(c.Path, syntax)
+		if err != nil {
+			return nil, err
+		}
+		leaf.segments = segments
+	}
+	return leaf, nil
+}
+
+// test evaluates the condition against node, a value inside the document root
+func (c *condition) test(root interface{}, node match) bool {
+	switch {
+	case c.all != nil:
+		for _, child := range c.all {
+			if !child.test(root, node) {
+				return false
+			}
+		}
+		return true
+
+	case c.any != nil:
+		for _, child := range c.any {
+			if child.test(root, node) {
+				return true
+			}
+		}
+		return false
+
+	case c.not != nil:
+		return !c.not.test(root, node)
+
+	default:
+		return c.cmp.match(childValues(root, node, c.segments))
+	}
+}
+
+// comparison tests the values found at a condition path against a configured value;
+// regular expressions and glob patterns are compiled once when the comparison is built
+type comparison struct {
+	op         Operator
+	value      interface{}
+	ignoreCase bool
+	pattern    *regexp.Regexp
+}
+
+// newComparison validates the configured value for op and compiles any pattern it needs
+func newComparison(op Operator, value interface{}, ignoreCase bool) (*comparison, error) {
+	c := &comparison{op: op, value: value, ignoreCase: ignoreCase}
+
+	switch op {
+	case OpEquals, OpNotEquals, OpGreaterThan, OpGreaterOrEqual, OpLessThan, OpLessOrEqual,
+		OpExists, OpNotExists, OpIsNull, OpNonEmpty:
+
+	case OpIsType:
+		name, ok := value.(string)
+		if !ok || !isTypeName(name) {
+			return nil, ErrInvalidRule
+		}
+
+	case OpIn, OpNotIn:
+		candidates := reflect.ValueOf(value)
+		if value == nil || !isList(candidates) {
+			return nil, ErrInvalidRule
+		}
+
+	case OpRegex, OpGlob, OpStartsWith, OpEndsWith, OpContains:
+		text, ok := value.(string)
+		if !ok {
+			return nil, ErrInvalidRule
+		}
+		if op == OpGlob {
+			text = globToRegexp(text)
+		}
+		if op == OpRegex || op == OpGlob {
+			if ignoreCase {
+				text = "(?i)" + text
+			}
+			pattern, err := regexp.Compile(text)
+			if err != nil {
+				return nil, ErrInvalidRule
+			}
+			c.pattern = pattern
+		}
+
+	default:
+		return nil, ErrInvalidRule
+	}
+
+	return c, nil
+}
+
+// match reports whether the values satisfy the comparison. A positive operator holds when any
+// of the values satisfies it; a negated operator (OpNotEquals, OpNotIn) holds when there is at
+// least one value and none of them satisfies the positive form
+func (c *comparison) match(values []interface{}) bool {
+	switch c.op {
+	case OpExists:
+		return len(values) > 0
+	case OpNotExists:
+		return len(values) == 0
+	}
+
+	positive := c.op
+	switch c.op {
+	case OpNotEquals:
+		positive = OpEquals
+	case OpNotIn:
+		positive = OpIn
+	}
+
+	found := false
+	for _, value := range values {
+		if c.matchValue(value, positive) {
+			found = true
+			break
+		}
+	}
+
+	if positive != c.op {
+		return len(values) > 0 && !found
+	}
+	return found
+}
+
+// matchValue compares a single value with the configured value using a positive operator
+func (c *comparison) matchValue(value interface{}, op Operator) bool {
+	switch op {
+	case OpEquals:
+		return valueEquals(value, c.value)
+
+	case OpGreaterThan, OpGreaterOrEqual, OpLessThan, OpLessOrEqual:
+		order, ok := compareValues(value, c.value)
+		if !ok {
+			return false
+		}
+		switch op {
+		case OpGreaterThan:
+			return order > 0
+		case OpGreaterOrEqual:
+			return order >= 0
+		case OpLessThan:
+			return order < 0
+		default:
+			return order <= 0
+		}
+
+	case OpIn:
+		candidates := reflect.ValueOf(c.value)
+		for i := 0; i < candidates.Len(); i++ {
+			if valueEquals(value, candidates.Index(i).Interface()) {
+				return true
+			}
+		}
+		return false
+
+	case OpRegex, OpGlob:
+		text, ok := value.(string)
+		return ok && c.pattern.MatchString(text)
+
+	case OpIsNull:
+		return value == nil
+
+	case OpIsType:
+		return typeName(value) == c.value
+
+	case OpNonEmpty:
+		switch v := value.(type) {
+		case nil:
+			return false
+		case string:
+			return v != ""
+		case []interface{}:
+			return len(v) > 0
+		case map[string]interface{}:
+			return len(v) > 0
+		default:
+			return true
+		}
+
+	case OpStartsWith, OpEndsWith, OpContains:
+		text, ok := value.(string)
+		if !ok {
+			return false
+		}
+		expected := c.value.(string)
+		if c.ignoreCase {
+			text, expected = strings.ToLower(text), strings.ToLower(expected)
+		}
+		switch op {
+		case OpStartsWith:
+			return strings.HasPrefix(text, expected)
+		case OpEndsWith:
+			return strings.HasSuffix(text, expected)
+		default:
+			return strings.Contains(text, expected)
+		}
+
+	default:
+		return false
+	}
+}
+
+// typeName returns the JSON type of a decoded value: null, string, number, bool, object or array
+func typeName(value interface{}) string {
+	if isNumber(value) {
+		return "number"
+	}
+
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	default:
+		return ""
+	}
+}
+
+// isTypeName reports whether name is a type accepted by OpIsType
+func isTypeName(name string) bool {
+	switch name {
+	case "string", "number", "bool", "object", "array":
+		return true
+	default:
+		return false
+	}
+}
+
+// globToRegexp translates a glob pattern into an anchored regular expression: * matches any run
+// of characters, ? a single character and [...] (or [!...]) a character class
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("(?s)^")
+
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+			}
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+
+	b.WriteString("$")
+	return b.String()
+}
+
+// compareValues orders two numbers numerically or two strings lexicographically;
+// the boolean result is false when the values are not comparable
+func compareValues(a, b interface{}) (int, bool) {
+	if isNumber(a) {
+		return compareNumbers(a, b)
+	}
+
+	if x, ok := a.(string); ok {
+		y, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(x, y), true
+	}
+
+	return 0, false
+}
+
+// valueEquals checks if two values are equal; numbers are compared by value whatever
+// their Go representation, so 1, int64(1), 1.0 and json.Number("1e0") are all equal
+func valueEquals(a, b interface{}) bool {
+	if isNumber(a) {
+		order, ok := compareNumbers(a, b)
+		return ok && order == 0
+	}
+
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	switch {
+	case isList(va) && isList(vb):
+		if va.Len() != vb.Len() {
+			return false
+		}
+		for i := 0; i < va.Len(); i++ {
+			if !valueEquals(va.Index(i).Interface(), vb.Index(i).Interface()) {
+				return false
+			}
+		}
+		return true
+
+	case va.Kind() == reflect.Map && vb.Kind() == reflect.Map:
+		if va.Len() != vb.Len() || va.Type().Key().Kind() != reflect.String || vb.Type().Key().Kind() != reflect.String {
+			return reflect.DeepEqual(a, b)
+		}
+		for _, key := range va.MapKeys() {
+			other := vb.MapIndex(reflect.ValueOf(key.String()).Convert(vb.Type().Key()))
+			if !other.IsValid() || !valueEquals(va.MapIndex(key).Interface(), other.Interface()) {
+				return false
+			}
+		}
+		return true
+
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+func isList(v reflect.Value) bool {
+	return v.Kind() == reflect.Slice || v.Kind() == reflect.Array
+}
+
+// maxNumberExponent bounds the decimal exponent of a json.Number that is compared exactly,
+// since expanding 1e1000000000 into a rational number would take gigabytes
+const maxNumberExponent = 10000
+
+// isNumber reports whether v is of a Go numeric type or a json.Number
+func isNumber(v interface{}) bool {
+	switch v.(type) {
+	case float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, json.Number:
+		return true
+	default:
+		return false
+	}
+}
+
+// compareNumbers orders two numbers exactly, so int64 IDs beyond 2^53 are not rounded;
+// the boolean result is false when either value is not a (finite) number
+func compareNumbers(a, b interface{}) (int, bool) {
+	x, ok := toRat(a)
+	if !ok {
+		return 0, false
+	}
+	y, ok := toRat(b)
+	if !ok {
+		return 0, false
+	}
+	return x.Cmp(y), true
+}
+
+// toRat converts any Go numeric type or json.Number to an exact rational number
+func toRat(v interface{}) (*big.Rat, bool) {
+	switch n := v.(type) {
+	case float64:
+		r := new(big.Rat).SetFloat64(n)
+		return r, r != nil
+	case float32:
+		r := new(big.Rat).SetFloat64(float64(n))
+		return r, r != nil
+	case int:
+		return new(big.Rat).SetInt64(int64(n)), true
+	case int8:
+		return new(big.Rat).SetInt64(int64(n)), true
+	case int16:
+		return new(big.Rat).SetInt64(int64(n)), true
+	case int32:
+		return new(big.Rat).SetInt64(int64(n)), true
+	case int64:
+		return new(big.Rat).SetInt64(n), true
+	case uint:
+		return new(big.Rat).SetUint64(uint64(n)), true
+	case uint8:
+		return new(big.Rat).SetUint64(uint64(n)), true
+	case uint16:
+		return new(big.Rat).SetUint64(uint64(n)), true
+	case uint32:
+		return new(big.Rat).SetUint64(uint64(n)), true
+	case uint64:
+		return new(big.Rat).SetUint64(n), true
+	case json.Number:
+		text := string(n)
+		if !isJSONNumber(text) {
+			return nil, false
+		}
+		if i := strings.IndexAny(text, "eE"); i >= 0 {
+			exp, err := strconv.Atoi(text[i+1:])
+			if err != nil || exp > maxNumberExponent || exp < -maxNumberExponent {
+				return nil, false
+			}
+		}
+		return new(big.Rat).SetString(text)
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,388 @@
+package cutjson
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestComparisonOperators(t *testing.T) {
+	jsonData := []byte(`{
+		"user": {"name": "John Doe", "age": 30},
+		"products": [
+			{"id": 101, "name": "Laptop", "price": 999.99},
+			{"id": 102, "name": "Mouse", "price": 25},
+			{"id": 103, "name": "Keyboard", "price": 75.5},
+			{"id": 104, "name": "Cable"}
+		]
+	}`)
+
+	cut := func(rules ...Rule) string {
+		result, err := CutWithRules(jsonData, rules)
+		So(err, ShouldBeNil)
+		return string(result)
+	}
+
+	Convey("测试值匹配规则的比较操作符", t, func() {
+		Convey("数值比较不区分Go中的数字类型", func() {
+			So(cut(NewKeepParentIfValueMatchesRule("user.age", 30)), ShouldEqual, `{"user":{"age":30,"name":"John Doe"}}`)
+			So(cut(NewKeepParentIfValueMatchesRule("user.age", int64(30))), ShouldEqual, `{"user":{"age":30,"name":"John Doe"}}`)
+			So(cut(NewKeepParentIfValueMatchesRule("user.age", json.Number("30.0"))), ShouldEqual, `{"user":{"age":30,"name":"John Doe"}}`)
+			So(cut(NewKeepArrayElementsIfChildValueMatchesRule("products", "id", 102)), ShouldEqual,
+				`{"products":[{"id":102,"name":"Mouse","price":25}]}`)
+		})
+
+		Convey("大小比较", func() {
+			So(cut(NewKeepArrayElementsIfChildValueComparesRule("products", "price", OpGreaterThan, 75.5)), ShouldEqual,
+				`{"products":[{"id":101,"name":"Laptop","price":999.99}]}`)
+			So(cut(NewKeepArrayElementsIfChildValueComparesRule("products", "price", OpGreaterOrEqual, 75.5)), ShouldEqual,
+				`{"products":[{"id":101,"name":"Laptop","price":999.99},{"id":103,"name":"Keyboard","price":75.5}]}`)
+			So(cut(NewKeepArrayElementsIfChildValueComparesRule("products", "price", OpLessThan, 75.5)), ShouldEqual,
+				`{"products":[{"id":102,"name":"Mouse","price":25}]}`)
+			So(cut(NewKeepArrayElementsIfChildValueComparesRule("products", "price", OpLessOrEqual, 25)), ShouldEqual,
+				`{"products":[{"id":102,"name":"Mouse","price":25}]}`)
+			So(cut(NewKeepParentIfValueComparesRule("user.age", OpGreaterOrEqual, 18)), ShouldEqual, `{"user":{"age":30,"name":"John Doe"}}`)
+		})
+
+		Convey("字符串按字典序比较，与数字不可比较", func() {
+			So(cut(NewKeepArrayElementsIfChildValueComparesRule("products", "name", OpLessThan, "Keyboard")), ShouldEqual,
+				`{"products":[{"id":104,"name":"Cable"}]}`)
+			So(cut(NewKeepArrayElementsIfChildValueComparesRule("products", "name", OpGreaterThan, 100)), ShouldEqual, `{}`)
+		})
+
+		Convey("不等于要求值存在", func() {
+			So(cut(NewKeepArrayElementsIfChildValueComparesRule("products", "price", OpNotEquals, 25)), ShouldEqual,
+				`{"products":[{"id":101,"name":"Laptop","price":999.99},{"id":103,"name":"Keyboard","price":75.5}]}`)
+		})
+
+		Convey("in和not_in", func() {
+			So(cut(NewKeepArrayElementsIfChildValueComparesRule("products", "id", OpIn, []int{101, 104})), ShouldEqual,
+				`{"products":[{"id":101,"name":"Laptop","price":999.99},{"id":104,"name":"Cable"}]}`)
+			So(cut(NewKeepArrayElementsIfChildValueComparesRule("products", "name", OpNotIn, []interface{}{"Laptop", "Mouse"})), ShouldEqual,
+				`{"products":[{"id":103,"name":"Keyboard","price":75.5},{"id":104,"name":"Cable"}]}`)
+		})
+
+		Convey("in的配置值不是数组时返回错误", func() {
+			_, err := CutWithRules(jsonData, []Rule{NewKeepParentIfValueComparesRule("user.age", OpIn, 30)})
+			So(err, ShouldEqual, ErrInvalidRule)
+		})
+	})
+}
+
+func TestStringMatchingOperators(t *testing.T) {
+	jsonData := []byte(`{
+		"orders": [
+			{"id": 1, "status": "SHIPPED"},
+			{"id": 2, "status": "shipping"},
+			{"id": 3, "status": "cancelled"},
+			{"id": 4, "status": 404}
+		],
+		"users": [
+			{"name": "alice", "email": "alice@example.com"},
+			{"name": "bob", "email": "bob@Example.COM"},
+			{"name": "carol", "email": "carol@other.org"}
+		]
+	}`)
+
+	ids := func(rule Rule) string {
+		result, err := CutWithRules(jsonData, []Rule{rule})
+		So(err, ShouldBeNil)
+		return string(result)
+	}
+
+	Convey("测试字符串匹配操作符", t, func() {
+		Convey("正则表达式只匹配字符串值", func() {
+			So(ids(NewKeepArrayElementsIfChildValueComparesRule("orders", "status", OpRegex, "^ship")), ShouldEqual,
+				`{"orders":[{"id":2,"status":"shipping"}]}`)
+			So(ids(NewKeepArrayElementsIfChildValueComparesRule("orders", "status", OpRegex, "0")), ShouldEqual, `{}`)
+		})
+
+		Convey("忽略大小写", func() {
+			rule := NewKeepArrayElementsIfChildValueComparesRule("orders", "status", OpRegex, "^ship")
+			rule.IgnoreCase = true
+			So(ids(rule), ShouldEqual, `{"orders":[{"id":1,"status":"SHIPPED"},{"id":2,"status":"shipping"}]}`)
+
+			rule = NewKeepArrayElementsIfChildValueComparesRule("users", "email", OpEndsWith, "@example.com")
+			So(ids(rule), ShouldEqual, `{"users":[{"email":"alice@example.com","name":"alice"}]}`)
+			rule.IgnoreCase = true
+			So(ids(rule), ShouldEqual,
+				`{"users":[{"email":"alice@example.com","name":"alice"},{"email":"bob@Example.COM","name":"bob"}]}`)
+		})
+
+		Convey("前缀、包含和通配符", func() {
+			So(ids(NewKeepArrayElementsIfChildValueComparesRule("users", "name", OpStartsWith, "ca")), ShouldEqual,
+				`{"users":[{"email":"carol@other.org","name":"carol"}]}`)
+			So(ids(NewKeepArrayElementsIfChildValueComparesRule("orders", "status", OpContains, "cel")), ShouldEqual,
+				`{"orders":[{"id":3,"status":"cancelled"}]}`)
+			So(ids(NewKeepArrayElementsIfChildValueComparesRule("users", "email", OpGlob, "*@*.org")), ShouldEqual,
+				`{"users":[{"email":"carol@other.org","name":"carol"}]}`)
+			So(ids(NewKeepArrayElementsIfChildValueComparesRule("users", "name", OpGlob, "[!a]o?")), ShouldEqual,
+				`{"users":[{"email":"bob@Example.COM","name":"bob"}]}`)
+		})
+
+		Convey("无效的模式返回错误", func() {
+			_, err := CutWithRules(jsonData, []Rule{NewKeepArrayElementsIfChildValueComparesRule("orders", "status", OpRegex, "(")})
+			So(err, ShouldEqual, ErrInvalidRule)
+			_, err = CutWithRules(jsonData, []Rule{NewKeepArrayElementsIfChildValueComparesRule("orders", "status", OpContains, 4)})
+			So(err, ShouldEqual, ErrInvalidRule)
+		})
+	})
+
+	Convey("测试通配符模式转换为正则表达式", t, func() {
+		So(globToRegexp("a*b?.c"), ShouldEqual, `(?s)^a.*b.\.c$`)
+		So(globToRegexp("[!0-9]x"), ShouldEqual, `(?s)^[^0-9]x$`)
+		So(globToRegexp(`\*[`), ShouldEqual, `(?s)^\*\[$`)
+	})
+}
+
+func TestExistenceOperators(t *testing.T) {
+	jsonData := []byte(`{
+		"user": {"name": "John Doe", "nickname": null},
+		"items": [
+			{"id": 1, "tags": ["a"], "note": "hi"},
+			{"id": 2, "tags": [], "note": ""},
+			{"id": 3, "note": null},
+			{"id": 4, "tags": {}, "note": 5}
+		]
+	}`)
+
+	cut := func(rule Rule) string {
+		result, err := CutWithRules(jsonData, []Rule{rule})
+		So(err, ShouldBeNil)
+		return string(result)
+	}
+
+	Convey("测试存在性和类型检查操作符", t, func() {
+		Convey("字段存在时保留父路径，值为null也算存在", func() {
+			So(cut(NewKeepParentIfValueComparesRule("user.nickname", OpExists, nil)), ShouldEqual,
+				`{"user":{"name":"John Doe","nickname":null}}`)
+			So(cut(NewKeepParentIfValueComparesRule("user.email", OpExists, nil)), ShouldEqual, `{}`)
+		})
+
+		Convey("字段不存在时保留父路径", func() {
+			So(cut(NewKeepParentIfValueComparesRule("user.email", OpNotExists, nil)), ShouldEqual,
+				`{"user":{"name":"John Doe","nickname":null}}`)
+			So(cut(NewKeepArrayElementsIfChildValueComparesRule("items", "tags", OpNotExists, nil)), ShouldEqual,
+				`{"items":[{"id":3,"note":null}]}`)
+			So(cut(NewKeepParentIfValueComparesRule("items.*.tags", OpNotExists, nil)), ShouldEqual,
+				`{"items":[{"id":3,"note":null}]}`)
+		})
+
+		Convey("null和类型检查", func() {
+			So(cut(NewKeepArrayElementsIfChildValueComparesRule("items", "note", OpIsNull, nil)), ShouldEqual,
+				`{"items":[{"id":3,"note":null}]}`)
+			So(cut(NewKeepArrayElementsIfChildValueComparesRule("items", "tags", OpIsType, "array")), ShouldEqual,
+				`{"items":[{"id":1,"note":"hi","tags":["a"]},{"id":2,"note":"","tags":[]}]}`)
+			So(cut(NewKeepArrayElementsIfChildValueComparesRule("items", "note", OpIsType, "number")), ShouldEqual,
+				`{"items":[{"id":4,"note":5,"tags":{}}]}`)
+		})
+
+		Convey("非空检查", func() {
+			So(cut(NewKeepArrayElementsIfChildValueComparesRule("items", "tags", OpNonEmpty, nil)), ShouldEqual,
+				`{"items":[{"id":1,"note":"hi","tags":["a"]}]}`)
+			So(cut(NewKeepArrayElementsIfChildValueComparesRule("items", "note", OpNonEmpty, nil)), ShouldEqual,
+				`{"items":[{"id":1,"note":"hi","tags":["a"]},{"id":4,"note":5,"tags":{}}]}`)
+		})
+
+		Convey("未知的类型名返回错误", func() {
+			_, err := CutWithRules(jsonData, []Rule{NewKeepArrayElementsIfChildValueComparesRule("items", "note", OpIsType, "integer")})
+			So(err, ShouldEqual, ErrInvalidRule)
+		})
+	})
+}
+
+func TestConditionTrees(t *testing.T) {
+	jsonData := []byte(`{
+		"user": {"name": "John Doe", "age": 30, "verified": true},
+		"products": [
+			{"id": 101, "category": "electronics", "inStock": true, "tags": ["sale"]},
+			{"id": 102, "category": "electronics", "inStock": false},
+			{"id": 103, "category": "accessories", "inStock": true},
+			{"id": 104, "category": "accessories", "inStock": false, "tags": ["new"]}
+		],
+		"limit": 102
+	}`)
+
+	cut := func(rule Rule) string {
+		result, err := CutWithRules(jsonData, []Rule{rule})
+		So(err, ShouldBeNil)
+		return string(result)
+	}
+
+	Convey("测试组合条件", t, func() {
+		Convey("all要求所有条件都满足", func() {
+			rule := NewKeepArrayElementsIfConditionRule("products", All(
+				Where("category", OpEquals, "electronics"),
+				Where("inStock", OpEquals, true),
+			))
+			So(cut(rule), ShouldEqual, `{"products":[{"category":"electronics","id":101,"inStock":true,"tags":["sale"]}]}`)
+		})
+
+		Convey("any和not", func() {
+			rule := NewKeepArrayElementsIfConditionRule("products", Any(
+				Where("id", OpEquals, 103),
+				Not(Where("tags", OpNotExists, nil)),
+			))
+			So(cut(rule), ShouldEqual,
+				`{"products":[{"category":"electronics","id":101,"inStock":true,"tags":["sale"]},{"category":"accessories","id":103,"inStock":true},{"category":"accessories","id":104,"inStock":false,"tags":["new"]}]}`)
+		})
+
+		Convey("空路径的叶子条件比较上下文节点本身", func() {
+			rule := NewKeepArrayElementsIfConditionRule("products.*.tags", Where("", OpEquals, "new"))
+			So(cut(rule), ShouldEqual, `{"products":[{"tags":["new"]}]}`)
+		})
+
+		Convey("保留父路径规则的条件相对于where指定的节点", func() {
+			rule := NewKeepParentIfConditionRule("user", All(
+				Where("verified", OpEquals, true),
+				Where("age", OpGreaterOrEqual, 18),
+			))
+			So(cut(rule), ShouldEqual, `{"user":{"age":30,"name":"John Doe","verified":true}}`)
+
+			rule = NewKeepParentIfConditionRule("user", Where("age", OpLessThan, 18))
+			So(cut(rule), ShouldEqual, `{}`)
+		})
+
+		Convey("条件中的JSONPath过滤器可以引用文档根", func() {
+			rule := NewKeepArrayElementsIfConditionRule("products", Where("@[?@ == $.limit]", OpExists, nil))
+			So(cut(rule), ShouldEqual, `{"products":[{"category":"electronics","id":102,"inStock":false}]}`)
+		})
+
+		Convey("同时指定多种组合方式返回错误", func() {
+			rule := NewKeepArrayElementsIfConditionRule("products", Condition{All: []Condition{}, Not: &Condition{}})
+			_, err := CutWithRules(jsonData, []Rule{rule})
+			So(err, ShouldEqual, ErrInvalidRule)
+		})
+	})
+}
+
+func TestLoadConfigOperators(t *testing.T) {
+	load := func(config string) ([]Rule, error) {
+		configFile, err := os.CreateTemp(t.TempDir(), "config-*.json")
+		So(err, ShouldBeNil)
+		_, err = configFile.WriteString(config)
+		So(err, ShouldBeNil)
+		So(configFile.Close(), ShouldBeNil)
+		return LoadRulesFromConfig(configFile.Name())
+	}
+
+	Convey("测试从配置文件加载比较操作符", t, func() {
+		rules, err := load(`{"rules": [
+			{"type": "keep_parent_if_value_matches", "where": "user.age", "op": "gte", "value": 18},
+			{"type": "keep_array_elements_if_child_value_matches", "where": "products", "child_path": "id", "op": "not_in", "value": [101, 102]}
+		]}`)
+		So(err, ShouldBeNil)
+		So(rules[0].Op, ShouldEqual, OpGreaterOrEqual)
+		So(rules[1].Op, ShouldEqual, OpNotIn)
+
+		_, err = load(`{"rules": [{"type": "keep_parent_if_value_matches", "where": "user.age", "op": "in", "value": 18}]}`)
+		So(err, ShouldNotBeNil)
+
+		_, err = load(`{"rules": [{"type": "keep_parent_if_value_matches", "where": "user.age", "op": "between", "value": 18}]}`)
+		So(err, ShouldNotBeNil)
+
+		rules, err = load(`{"rules": [
+			{"type": "keep_array_elements_if_child_value_matches", "where": "users", "child_path": "email", "op": "regex", "value": "@example\\.com$", "ignore_case": true}
+		]}`)
+		So(err, ShouldBeNil)
+		So(rules[0].Op, ShouldEqual, OpRegex)
+		So(rules[0].IgnoreCase, ShouldBeTrue)
+		So(rules[0].compiled.pattern.String(), ShouldEqual, `(?i)@example\.com$`)
+
+		_, err = load(`{"rules": [{"type": "keep_parent_if_value_matches", "where": "user.email", "op": "regex", "value": "("}]}`)
+		So(err, ShouldNotBeNil)
+
+		rules, err = load(`{"rules": [{"type": "keep_parent_if_value_matches", "where": "user.email", "op": "exists"}]}`)
+		So(err, ShouldBeNil)
+		So(rules[0].Op, ShouldEqual, OpExists)
+
+		_, err = load(`{"rules": [{"type": "keep_parent_if_value_matches", "where": "user.email", "op": "is_type", "value": "integer"}]}`)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("测试从配置文件加载组合条件", t, func() {
+		rules, err := load(`{"rules": [{
+			"type": "keep_array_elements_if_child_value_matches",
+			"where": "products",
+			"condition": {"all": [
+				{"path": "category", "op": "equals", "value": "electronics"},
+				{"not": {"path": "inStock", "op": "equals", "value": false}}
+			]}
+		}]}`)
+		So(err, ShouldBeNil)
+		So(rules[0].Condition, ShouldResemble, &Condition{All: []Condition{
+			Where("category", OpEquals, "electronics"),
+			Not(Where("inStock", OpEquals, false)),
+		}})
+		So(rules[0].compiledCondition, ShouldNotBeNil)
+
+		_, err = load(`{"rules": [{"type": "keep_parent_if_value_matches", "where": "user", "condition": {"all": [], "path": "age"}}]}`)
+		So(err, ShouldNotBeNil)
+
+		_, err = load(`{"rules": [{"type": "keep_parent_if_value_matches", "where": "user", "condition": {"path": "age"}}]}`)
+		So(err, ShouldNotBeNil)
+
+		_, err = load(`{"rules": [{"type": "keep_parent_if_value_matches", "where": "user", "op": "equals", "condition": {"path": "age", "op": "exists"}}]}`)
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestLosslessNumbers(t *testing.T) {
+	jsonData := []byte(`{
+		"orders": [
+			{"id": 9007199254740993, "total": 1.50, "rate": 1e0},
+			{"id": 9007199254740992, "total": 100, "rate": 2.5E-1}
+		]
+	}`)
+
+	cut := func(rules ...Rule) string {
+		result, err := CutWithRules(jsonData, rules)
+		So(err, ShouldBeNil)
+		return string(result)
+	}
+
+	Convey("测试数字不丢失精度", t, func() {
+		Convey("数字按源文档中的文本原样输出", func() {
+			So(cut(NewKeepPathRule("orders")), ShouldEqual,
+				`{"orders":[{"id":9007199254740993,"rate":1e0,"total":1.50},{"id":9007199254740992,"rate":2.5E-1,"total":100}]}`)
+		})
+
+		Convey("超过2^53的整数按精确值比较", func() {
+			So(cut(NewKeepArrayElementsIfChildValueMatchesRule("orders", "id", int64(9007199254740993))), ShouldEqual,
+				`{"orders":[{"id":9007199254740993,"rate":1e0,"total":1.50}]}`)
+			So(cut(NewKeepArrayElementsIfChildValueMatchesRule("orders", "id", json.Number("9007199254740992"))), ShouldEqual,
+				`{"orders":[{"id":9007199254740992,"rate":2.5E-1,"total":100}]}`)
+			So(cut(NewKeepArrayElementsIfChildValueComparesRule("orders", "id", OpGreaterThan, uint64(9007199254740992))), ShouldEqual,
+				`{"orders":[{"id":9007199254740993,"rate":1e0,"total":1.50}]}`)
+			So(cut(NewKeepPathRule("$.orders[?@.id == 9007199254740993].id")), ShouldEqual, `{"orders":[{"id":9007199254740993}]}`)
+		})
+
+		Convey("不同写法的相同数值相等", func() {
+			So(cut(NewKeepArrayElementsIfChildValueMatchesRule("orders", "rate", 1)), ShouldEqual,
+				`{"orders":[{"id":9007199254740993,"rate":1e0,"total":1.50}]}`)
+			So(cut(NewKeepArrayElementsIfChildValueMatchesRule("orders", "total", json.Number("1e2"))), ShouldEqual,
+				`{"orders":[{"id":9007199254740992,"rate":2.5E-1,"total":100}]}`)
+			So(cut(NewKeepArrayElementsIfChildValueComparesRule("orders", "rate", OpIn, []interface{}{0.25})), ShouldEqual,
+				`{"orders":[{"id":9007199254740992,"rate":2.5E-1,"total":100}]}`)
+			So(valueEquals(json.Number("1"), json.Number("1.0")), ShouldBeTrue)
+			So(valueEquals(json.Number("1e0"), 1.0), ShouldBeTrue)
+			So(valueEquals(json.Number("1e100000"), json.Number("1e100000")), ShouldBeFalse)
+		})
+
+		Convey("配置文件中的数值也不丢失精度", func() {
+			configFile, err := os.CreateTemp("", "cutjson-config-*.json")
+			So(err, ShouldBeNil)
+			defer os.Remove(configFile.Name())
+
+			_, err = configFile.WriteString(`{"rules": [{"type": "keep_array_elements_if_child_value_matches", "where": "orders", "child_path": "id", "op": "equals", "value": 9007199254740993}]}`)
+			So(err, ShouldBeNil)
+			So(configFile.Close(), ShouldBeNil)
+
+			rules, err := LoadRulesFromConfig(configFile.Name())
+			So(err, ShouldBeNil)
+			So(cut(rules...), ShouldEqual, `{"orders":[{"id":9007199254740993,"rate":1e0,"total":1.50}]}`)
+		})
+	})
+}
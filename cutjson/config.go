@@ -1,7 +1,6 @@
 package cutjson
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -9,30 +8,71 @@ import (
 
 // RuleConfig 表示JSON配置文件中的单个规则配置
 type RuleConfig struct {
-	Type      string      `json:"type"`
-	Where     string      `json:"where"`
-	ChildPath string      `json:"child_path,omitempty"`
-	Op        string      `json:"op,omitempty"`
-	Value     interface{} `json:"value,omitempty"`
+	Type       string           `json:"type"`
+	Where      string           `json:"where"`
+	ChildPath  string           `json:"child_path,omitempty"`
+	Op         string           `json:"op,omitempty"`
+	Value      interface{}      `json:"value,omitempty"`
+	IgnoreCase bool             `json:"ignore_case,omitempty"`
+	Condition  *ConditionConfig `json:"condition,omitempty"`
+	Mask       *MaskConfig      `json:"mask,omitempty"`
+	Fields     []string         `json:"fields,omitempty"`
+	PathSyntax string           `json:"path_syntax,omitempty"`
+}
+
+// ConditionConfig 表示配置文件中的组合条件: all、any、not 三者之一，或者由path、op、value组成的叶子条件
+type ConditionConfig struct {
+	All        []ConditionConfig `json:"all,omitempty"`
+	Any        []ConditionConfig `json:"any,omitempty"`
+	Not        *ConditionConfig  `json:"not,omitempty"`
+	Path       string            `json:"path,omitempty"`
+	Op         string            `json:"op,omitempty"`
+	Value      interface{}       `json:"value,omitempty"`
+	IgnoreCase bool              `json:"ignore_case,omitempty"`
+}
+
+// MaskConfig 表示配置文件中mask_path规则的替换方式
+type MaskConfig struct {
+	Mode     string `json:"mode"`
+	Text     string `json:"text,omitempty"`
+	KeepLast int    `json:"keep_last,omitempty"`
+	Salt     string `json:"salt,omitempty"`
+}
+
+// OptionsConfig 表示JSON配置文件中的裁剪选项
+type OptionsConfig struct {
+	ArrayMode        string      `json:"array_mode,omitempty"`
+	Placeholder      interface{} `json:"placeholder,omitempty"`
+	Conflict         string      `json:"conflict,omitempty"`
+	KeepAll          bool        `json:"keep_all,omitempty"`
+	PreserveKeyOrder bool        `json:"preserve_key_order,omitempty"`
+	InvalidLines     string      `json:"invalid_lines,omitempty"`
 }
 
 // RulesConfig 表示整个JSON配置文件的结构
 type RulesConfig struct {
-	Rules []RuleConfig `json:"rules"`
+	Options *OptionsConfig `json:"options,omitempty"`
+	Rules   []RuleConfig   `json:"rules"`
 }
 
 // LoadRulesFromConfig 从JSON配置文件加载规则
 func LoadRulesFromConfig(configPath string) ([]Rule, error) {
+	rules, _, err := LoadConfig(configPath)
+	return rules, err
+}
+
+// LoadConfig 从JSON配置文件加载规则和裁剪选项
+func LoadConfig(configPath string) ([]Rule, Options, error) {
 	// 读取配置文件
 	configData, err := os.ReadFile(configPath)
 	if err != nil {
-		return nil, fmt.Errorf("无法读取配置文件: %w", err)
+		return nil, Options{}, fmt.Errorf("无法读取配置文件: %w", err)
 	}
 
 	// 解析配置文件
 	var config RulesConfig
-	if err := json.Unmarshal(configData, &config); err != nil {
-		return nil, fmt.Errorf("无法解析配置文件: %w", err)
+	if err := decode(configData, &config); err != nil {
+		return nil, Options{}, fmt.Errorf("无法解析配置文件: %w", err)
 	}
 
 	// 构建规则列表
@@ -40,42 +80,387 @@ func LoadRulesFromConfig(configPath string) ([]Rule, error) {
 	for _, ruleConfig := range config.Rules {
 		rule, err := buildRuleFromConfig(ruleConfig)
 		if err != nil {
-			return nil, err
+			return nil, Options{}, err
 		}
 		rules = append(rules, rule)
 	}
 
-	return rules, nil
+	// 构建裁剪选项
+	var opts Options
+	if config.Options != nil {
+		opts, err = buildOptionsFromConfig(*config.Options)
+		if err != nil {
+			return nil, Options{}, err
+		}
+	}
+
+	return rules, opts, nil
+}
+
+// buildOptionsFromConfig 根据配置构建裁剪选项
+func buildOptionsFromConfig(config OptionsConfig) (Options, error) {
+	var opts Options
+
+	if config.ArrayMode != "" {
+		mode, err := ParseArrayMode(config.ArrayMode)
+		if err != nil {
+			return Options{}, err
+		}
+		opts.ArrayMode = mode
+	}
+
+	opts.Placeholder = config.Placeholder
+
+	if config.Conflict != "" {
+		policy, err := ParseConflictPolicy(config.Conflict)
+		if err != nil {
+			return Options{}, err
+		}
+		opts.Conflict = policy
+	}
+
+	opts.KeepAll = config.KeepAll
+	opts.PreserveKeyOrder = config.PreserveKeyOrder
+
+	if config.InvalidLines != "" {
+		policy, err := ParseInvalidLinePolicy(config.InvalidLines)
+		if err != nil {
+			return Options{}, err
+		}
+		opts.InvalidLines = policy
+	}
+
+	return opts, nil
+}
+
+// ParseArrayMode 解析数组输出方式的名称: compact 或 preserve
+func ParseArrayMode(name string) (ArrayMode, error) {
+	switch name {
+	case "compact":
+		return CompactArrays, nil
+	case "preserve":
+		return PreserveArrayPositions, nil
+	default:
+		return 0, fmt.Errorf("未知的数组输出方式: %s", name)
+	}
+}
+
+// ParsePathSyntax 解析路径语法的名称: auto（默认）、dotted、pointer、jsonpath、fields 或 fieldmask
+func ParsePathSyntax(name string) (PathSyntax, error) {
+	switch name {
+	case "", "auto":
+		return PathAuto, nil
+	case "dotted":
+		return PathDotted, nil
+	case "pointer":
+		return PathPointer, nil
+	case "jsonpath":
+		return PathJSONPath, nil
+	case "fields":
+		return PathFields, nil
+	case "fieldmask":
+		return PathFieldMask, nil
+	default:
+		return 0, fmt.Errorf("未知的路径语法: %s", name)
+	}
+}
+
+// ParseConflictPolicy 解析合并策略的名称: union、last-wins、first-wins 或 intersection
+func ParseConflictPolicy(name string) (ConflictPolicy, error) {
+	switch name {
+	case "union":
+		return ConflictUnion, nil
+	case "last-wins":
+		return ConflictLastWins, nil
+	case "first-wins":
+		return ConflictFirstWins, nil
+	case "intersection":
+		return ConflictIntersection, nil
+	default:
+		return 0, fmt.Errorf("未知的合并策略: %s", name)
+	}
+}
+
+// ParseInvalidLinePolicy 解析NDJSON中无效行处理方式的名称: abort、skip 或 null
+func ParseInvalidLinePolicy(name string) (InvalidLinePolicy, error) {
+	switch name {
+	case "abort":
+		return InvalidLineAbort, nil
+	case "skip":
+		return InvalidLineSkip, nil
+	case "null":
+		return InvalidLineNull, nil
+	default:
+		return 0, fmt.Errorf("未知的无效行处理方式: %s", name)
+	}
+}
+
+// ParseMaskMode 解析替换方式的名称: fixed、keep_last、hash 或 null
+func ParseMaskMode(name string) (MaskMode, error) {
+	switch name {
+	case "fixed":
+		return MaskFixed, nil
+	case "keep_last":
+		return MaskKeepLast, nil
+	case "hash":
+		return MaskHash, nil
+	case "null":
+		return MaskNull, nil
+	default:
+		return 0, fmt.Errorf("未知的替换方式: %s", name)
+	}
+}
+
+// ParseOperator 解析比较操作符的名称: equals、not_equals、gt、gte、lt、lte、in、not_in、
+// regex、starts_with、ends_with、contains、glob、exists、not_exists、is_null、is_type 或 non_empty
+func ParseOperator(name string) (Operator, error) {
+	switch name {
+	case "equals":
+		return OpEquals, nil
+	case "not_equals":
+		return OpNotEquals, nil
+	case "gt":
+		return OpGreaterThan, nil
+	case "gte":
+		return OpGreaterOrEqual, nil
+	case "lt":
+		return OpLessThan, nil
+	case "lte":
+		return OpLessOrEqual, nil
+	case "in":
+		return OpIn, nil
+	case "not_in":
+		return OpNotIn, nil
+	case "regex":
+		return OpRegex, nil
+	case "starts_with":
+		return OpStartsWith, nil
+	case "ends_with":
+		return OpEndsWith, nil
+	case "contains":
+		return OpContains, nil
+	case "glob":
+		return OpGlob, nil
+	case "exists":
+		return OpExists, nil
+	case "not_exists":
+		return OpNotExists, nil
+	case "is_null":
+		return OpIsNull, nil
+	case "is_type":
+		return OpIsType, nil
+	case "non_empty":
+		return OpNonEmpty, nil
+	default:
+		return 0, fmt.Errorf("未知的比较操作符: %s", name)
+	}
 }
 
 // buildRuleFromConfig 根据配置构建规则
 func buildRuleFromConfig(config RuleConfig) (Rule, error) {
+	syntax, err := ParsePathSyntax(config.PathSyntax)
+	if err != nil {
+		return Rule{}, err
+	}
+
+	var rule Rule
 	switch config.Type {
 	case "keep_path":
-		return NewKeepPathRule(config.Where), nil
+		rule = NewKeepPathRule(config.Where)
+
+	case "drop_path":
+		if config.Where == "" {
+			return Rule{}, errors.New("drop_path规则必须指定where字段")
+		}
+		rule = NewDropPathRule(config.Where)
+
+	case "mask_path":
+		if config.Where == "" {
+			return Rule{}, errors.New("mask_path规则必须指定where字段")
+		}
+		mask, err := buildMask(config.Mask)
+		if err != nil {
+			return Rule{}, err
+		}
+		rule = NewMaskPathRule(config.Where, mask)
 
 	case "keep_parent_if_value_matches":
 		if config.Where == "" {
 			return Rule{}, errors.New("keep_parent_if_value_matches规则必须指定where字段")
 		}
-		if config.Op != "equals" {
-			return Rule{}, errors.New("keep_parent_if_value_matches规则目前只支持equals操作符")
+		if config.Condition != nil {
+			rule, err = buildConditionRule(NewKeepParentIfConditionRule, config, syntax)
+			break
+		}
+		cmp, err := compileRuleComparison(config)
+		if err != nil {
+			return Rule{}, err
 		}
-		return NewKeepParentIfValueMatchesRule(config.Where, config.Value), nil
+		rule = NewKeepParentIfValueComparesRule(config.Where, cmp.op, config.Value)
+		rule.IgnoreCase = config.IgnoreCase
+		rule.compiled = cmp
 
-	case "keep_array_elements_if_child_value_matches":
+	case "keep_array_elements_if_child_value_matches", "drop_array_elements_if_child_value_matches":
 		if config.Where == "" {
-			return Rule{}, errors.New("keep_array_elements_if_child_value_matches规则必须指定where字段")
+			return Rule{}, fmt.Errorf("%s规则必须指定where字段", config.Type)
+		}
+		newConditionRule, newComparesRule := NewKeepArrayElementsIfConditionRule, NewKeepArrayElementsIfChildValueComparesRule
+		if config.Type == "drop_array_elements_if_child_value_matches" {
+			newConditionRule, newComparesRule = NewDropArrayElementsIfConditionRule, NewDropArrayElementsIfChildValueComparesRule
+		}
+		if config.Condition != nil {
+			rule, err = buildConditionRule(newConditionRule, config, syntax)
+			break
 		}
 		if config.ChildPath == "" {
-			return Rule{}, errors.New("keep_array_elements_if_child_value_matches规则必须指定child_path字段")
+			return Rule{}, fmt.Errorf("%s规则必须指定child_path字段", config.Type)
 		}
-		if config.Op != "equals" {
-			return Rule{}, errors.New("keep_array_elements_if_child_value_matches规则目前只支持equals操作符")
+		cmp, err := compileRuleComparison(config)
+		if err != nil {
+			return Rule{}, err
 		}
-		return NewKeepArrayElementsIfChildValueMatchesRule(config.Where, config.ChildPath, config.Value), nil
+		rule = newComparesRule(config.Where, config.ChildPath, cmp.op, config.Value)
+		rule.IgnoreCase = config.IgnoreCase
+		rule.compiled = cmp
 
 	default:
 		return Rule{}, fmt.Errorf("未知的规则类型: %s", config.Type)
 	}
+
+	if err != nil {
+		return Rule{}, err
+	}
+
+	if len(config.Fields) > 0 {
+		if rule.Type != KeepArrayElementsIfChildValueMatches {
+			return Rule{}, fmt.Errorf("%s规则不支持fields字段", config.Type)
+		}
+		rule.Fields = config.Fields
+	}
+
+	rule.PathSyntax = syntax
+	return rule, nil
+}
+
+// buildConditionRule 使用配置中的组合条件构建规则，并预先编译条件
+func buildConditionRule(newRule func(string, Condition) Rule, config RuleConfig, syntax PathSyntax) (Rule, error) {
+	if config.ChildPath != "" || config.Op != "" || config.Value != nil {
+		return Rule{}, fmt.Errorf("%s规则指定condition时不能再指定child_path、op或value字段", config.Type)
+	}
+
+	condition, err := buildCondition(*config.Condition)
+	if err != nil {
+		return Rule{}, err
+	}
+
+	compiled, err := compileCondition(condition, syntax)
+	if err != nil {
+		return Rule{}, fmt.Errorf("无效的condition: %v", err)
+	}
+
+	rule := newRule(config.Where, condition)
+	rule.compiledCondition = compiled
+	return rule, nil
+}
+
+// buildCondition 将配置中的组合条件转换为Condition
+func buildCondition(config ConditionConfig) (Condition, error) {
+	composites := 0
+	for _, set := range []bool{config.All != nil, config.Any != nil, config.Not != nil} {
+		if set {
+			composites++
+		}
+	}
+	isLeaf := config.Path != "" || config.Op != "" || config.Value != nil || config.IgnoreCase
+	if composites > 1 || (composites == 1 && isLeaf) {
+		return Condition{}, errors.New("condition只能指定all、any、not或叶子条件中的一种")
+	}
+
+	switch {
+	case config.All != nil || config.Any != nil:
+		children := config.All
+		if config.Any != nil {
+			children = config.Any
+		}
+		conditions := make([]Condition, 0, len(children))
+		for _, child := range children {
+			c, err := buildCondition(child)
+			if err != nil {
+				return Condition{}, err
+			}
+			conditions = append(conditions, c)
+		}
+		if config.All != nil {
+			return All(conditions...), nil
+		}
+		return Any(conditions...), nil
+
+	case config.Not != nil:
+		inner, err := buildCondition(*config.Not)
+		if err != nil {
+			return Condition{}, err
+		}
+		return Not(inner), nil
+	}
+
+	if config.Op == "" {
+		return Condition{}, errors.New("叶子条件必须指定op字段")
+	}
+	op, err := ParseOperator(config.Op)
+	if err != nil {
+		return Condition{}, err
+	}
+
+	return Condition{Path: config.Path, Op: op, Value: config.Value, IgnoreCase: config.IgnoreCase}, nil
+}
+
+// compileRuleComparison 解析规则的op字段并检查value是否符合操作符的要求，
+// 正则表达式和通配符模式在这里编译一次，应用规则时直接复用
+func compileRuleComparison(config RuleConfig) (*comparison, error) {
+	if config.Op == "" {
+		return nil, fmt.Errorf("%s规则必须指定op字段", config.Type)
+	}
+
+	op, err := ParseOperator(config.Op)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case OpIn, OpNotIn:
+		if _, ok := config.Value.([]interface{}); !ok {
+			return nil, fmt.Errorf("%s操作符的value必须是数组", config.Op)
+		}
+	case OpRegex, OpStartsWith, OpEndsWith, OpContains, OpGlob:
+		if _, ok := config.Value.(string); !ok {
+			return nil, fmt.Errorf("%s操作符的value必须是字符串", config.Op)
+		}
+	case OpIsType:
+		if name, ok := config.Value.(string); !ok || !isTypeName(name) {
+			return nil, errors.New("is_type操作符的value必须是string、number、bool、object或array之一")
+		}
+	}
+
+	cmp, err := newComparison(op, config.Value, config.IgnoreCase)
+	if err != nil {
+		return nil, fmt.Errorf("无效的%s模式: %v", config.Op, config.Value)
+	}
+	return cmp, nil
+}
+
+// buildMask 将配置中的替换方式转换为Mask，未配置时使用固定文本"***"
+func buildMask(config *MaskConfig) (Mask, error) {
+	if config == nil {
+		return Mask{}, nil
+	}
+
+	mode, err := ParseMaskMode(config.Mode)
+	if err != nil {
+		return Mask{}, err
+	}
+	if mode == MaskKeepLast && config.KeepLast <= 0 {
+		return Mask{}, errors.New("keep_last替换方式必须指定大于0的keep_last字段")
+	}
+
+	return Mask{Mode: mode, Text: config.Text, KeepLast: config.KeepLast, Salt: config.Salt}, nil
 }
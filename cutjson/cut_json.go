@@ -4,8 +4,6 @@ package cutjson
 import (
 	"encoding/json"
 	"errors"
-	"reflect"
-	"strings"
 )
 
 var (
@@ -17,14 +15,29 @@ var (
 	ErrInvalidPath = errors.New("invalid path format")
 	// ErrInvalidRule is returned when the rule format is invalid.
 	ErrInvalidRule = errors.New("invalid rule format")
+
+	// errNotArray is returned when the path of an array element rule matches a value that is not an array
+	errNotArray = errors.New("path does not point to an array")
 )
 
 // Rule represents a JSON cutting rule
 type Rule struct {
-	Type      RuleType    // 规则类型
-	Path      string      // JSON路径
-	Value     interface{} // 配置值（用于规则2和规则3）
-	ChildPath string      // 子路径（用于规则3）
+	Type       RuleType    // 规则类型
+	Path       string      // JSON路径
+	Value      interface{} // 配置值（用于规则2、3和6）
+	ChildPath  string      // 子路径（用于规则3和6）
+	Op         Operator    // 比较操作符（用于规则2、3和6）
+	IgnoreCase bool        // 字符串匹配操作符是否忽略大小写
+	Condition  *Condition  // 组合条件（用于规则2、3和6），设置后代替ChildPath、Op和Value
+	Mask       Mask        // 替换方式（用于规则5）
+	Fields     []string    // 匹配元素中要保留的子路径（用于规则3），为空时保留整个元素
+	PathSyntax PathSyntax  // Path、ChildPath和条件中路径的语法
+
+	compiled          *comparison // 加载规则时预先编译的比较条件
+	compiledCondition *condition  // 加载规则时预先编译的组合条件
+	compiledPath      []segment   // 编译规则时预先解析的路径
+	compiledElements  *condition  // 编译规则时预先构建的数组元素条件（用于规则3和6）
+	compiledFields    [][]segment // 编译规则时预先解析的Fields
 }
 
 // RuleType defines the type of cutting rule
@@ -37,6 +50,12 @@ const (
 	KeepParentIfValueMatches
 	// KeepArrayElementsIfChildValueMatches 规则3: 如果数组元素的子路径值匹配，保留该元素
 	KeepArrayElementsIfChildValueMatches
+	// DropPath 规则4: 从结果中移除指定路径
+	DropPath
+	// MaskPath 规则5: 替换结果中指定路径的值
+	MaskPath
+	// DropArrayElementsIfChildValueMatches 规则6: 如果数组元素的子路径值匹配，移除该元素
+	DropArrayElementsIfChildValueMatches
 )
 
 // NewKeepPathRule creates a rule to keep a specific JSON path
@@ -47,6 +66,23 @@ func NewKeepPathRule(path string) Rule {
 	}
 }
 
+// NewDropPathRule creates a rule to remove a specific JSON path from the result
+func NewDropPathRule(path string) Rule {
+	return Rule{
+		Type: DropPath,
+		Path: path,
+	}
+}
+
+// NewMaskPathRule creates a rule to replace the values at path in the result according to mask
+func NewMaskPathRule(path string, mask Mask) Rule {
+	return Rule{
+		Type: MaskPath,
+		Path: path,
+		Mask: mask,
+	}
+}
+
 // NewKeepParentIfValueMatchesRule creates a rule to keep parent path if child value matches
 func NewKeepParentIfValueMatchesRule(path string, value interface{}) Rule {
 	return Rule{
@@ -56,6 +92,13 @@ func NewKeepParentIfValueMatchesRule(path string, value interface{}) Rule {
 	}
 }
 
+// NewKeepParentIfValueComparesRule creates a rule to keep parent path if child value satisfies op against value
+func NewKeepParentIfValueComparesRule(path string, op Operator, value interface{}) Rule {
+	rule := NewKeepParentIfValueMatchesRule(path, value)
+	rule.Op = op
+	return rule
+}
+
 // NewKeepArrayElementsIfChildValueMatchesRule creates a rule to keep array elements if child value matches
 func NewKeepArrayElementsIfChildValueMatchesRule(arrayPath string, childPath string, value interface{}) Rule {
 	return Rule{
@@ -66,20 +109,86 @@ func NewKeepArrayElementsIfChildValueMatchesRule(arrayPath string, childPath str
 	}
 }
 
+// NewKeepArrayElementsIfChildValueComparesRule creates a rule to keep array elements if child value satisfies op against value
+func NewKeepArrayElementsIfChildValueComparesRule(arrayPath string, childPath string, op Operator, value interface{}) Rule {
+	rule := NewKeepArrayElementsIfChildValueMatchesRule(arrayPath, childPath, value)
+	rule.Op = op
+	return rule
+}
+
+// NewKeepParentIfConditionRule creates a rule to keep the values at path that satisfy condition;
+// the paths in condition are relative to each of those values
+func NewKeepParentIfConditionRule(path string, condition Condition) Rule {
+	return Rule{
+		Type:      KeepParentIfValueMatches,
+		Path:      path,
+		Condition: &condition,
+	}
+}
+
+// NewKeepArrayElementsIfConditionRule creates a rule to keep array elements that satisfy condition;
+// the paths in condition are relative to each element
+func NewKeepArrayElementsIfConditionRule(arrayPath string, condition Condition) Rule {
+	return Rule{
+		Type:      KeepArrayElementsIfChildValueMatches,
+		Path:      arrayPath,
+		Condition: &condition,
+	}
+}
+
+// NewDropArrayElementsIfChildValueMatchesRule creates a rule to remove array elements if child value matches
+func NewDropArrayElementsIfChildValueMatchesRule(arrayPath string, childPath string, value interface{}) Rule {
+	return Rule{
+		Type:      DropArrayElementsIfChildValueMatches,
+		Path:      arrayPath,
+		ChildPath: childPath,
+		Value:     value,
+	}
+}
+
+// NewDropArrayElementsIfChildValueComparesRule creates a rule to remove array elements if child value satisfies op against value
+func NewDropArrayElementsIfChildValueComparesRule(arrayPath string, childPath string, op Operator, value interface{}) Rule {
+	rule := NewDropArrayElementsIfChildValueMatchesRule(arrayPath, childPath, value)
+	rule.Op = op
+	return rule
+}
+
+// NewDropArrayElementsIfConditionRule creates a rule to remove array elements that satisfy condition;
+// the paths in condition are relative to each element
+func NewDropArrayElementsIfConditionRule(arrayPath string, condition Condition) Rule {
+	return Rule{
+		Type:      DropArrayElementsIfChildValueMatches,
+		Path:      arrayPath,
+		Condition: &condition,
+	}
+}
+
 // CutWithRules cuts a JSON object based on the provided rules
 func CutWithRules(jsonData []byte, rules []Rule) ([]byte, error) {
+	return CutWithOptions(jsonData, rules, Options{})
+}
+
+// CutWithOptions cuts a JSON object based on the provided rules, laying out the result according to opts
+func CutWithOptions(jsonData []byte, rules []Rule, opts Options) ([]byte, error) {
 	if len(jsonData) == 0 {
 		return nil, ErrInvalidJSON
 	}
 
-	// Parse the JSON data
+	// Parse the JSON data, keeping numbers as json.Number and recording the key order of every
+	// object if it is to be preserved
 	var data interface{}
-	if err := json.Unmarshal(jsonData, &data); err != nil {
+	var order keyOrder
+	if opts.PreserveKeyOrder {
+		var err error
+		if data, order, err = decodeOrdered(jsonData); err != nil {
+			return nil, err
+		}
+	} else if err := decode(jsonData, &data); err != nil {
 		return nil, ErrInvalidJSON
 	}
 
 	// Apply each rule
-	result, err := applyRules(data, rules)
+	result, err := applyRules(data, order, rules, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -93,264 +202,507 @@ func CutWithRules(jsonData []byte, rules []Rule) ([]byte, error) {
 	return output, nil
 }
 
-// applyRules applies all rules to the JSON data
-func applyRules(data interface{}, rules []Rule) (interface{}, error) {
-	result := make(map[string]interface{})
+// applyRules applies all rules to the JSON data. Drop rules are applied after every keep rule and
+// mask rules after that, so masks only rewrite values that made it into the result; when there
+// are no keep rules, or opts.KeepAll is set, the result starts as the whole document
+func applyRules(data interface{}, order keyOrder, rules []Rule, opts Options) (interface{}, error) {
+	result := newResultBuilder(data, opts.Conflict)
 
+	var keepRules, dropRules, maskRules []Rule
 	for _, rule := range rules {
 		switch rule.Type {
-		case KeepPath:
-			if err := applyKeepPathRule(data, rule.Path, result); err != nil && err != ErrPathNotFound {
-				return nil, err
-			}
+		case DropPath, DropArrayElementsIfChildValueMatches:
+			dropRules = append(dropRules, rule)
+		case MaskPath:
+			maskRules = append(maskRules, rule)
+		default:
+			keepRules = append(keepRules, rule)
+		}
+	}
 
-		case KeepParentIfValueMatches:
-			if err := applyKeepParentIfValueMatchesRule(data, rule.Path, rule.Value, result); err != nil && err != ErrPathNotFound {
-				return nil, err
-			}
+	if opts.KeepAll || (len(keepRules) == 0 && len(dropRules)+len(maskRules) > 0) {
+		result.keepDocument()
+	}
+
+	for i, rule := range keepRules {
+		// With the intersection policy every rule is evaluated on its own and then intersected
+		target := result
+		if opts.Conflict == ConflictIntersection {
+			target = newResultBuilder(data, ConflictUnion)
+		}
 
-		case KeepArrayElementsIfChildValueMatches:
-			if err := applyKeepArrayElementsIfChildValueMatchesRule(data, rule.Path, rule.ChildPath, rule.Value, result); err != nil && err != ErrPathNotFound {
-				return nil, err
+		if err := applyRule(data, rule, target); err != nil {
+			return nil, err
+		}
+
+		if opts.Conflict == ConflictIntersection {
+			if i == 0 && !opts.KeepAll {
+				result.root, result.present = target.root, target.present
+			} else {
+				result.intersect(target)
 			}
+		}
+	}
 
-		default:
-			return nil, ErrInvalidRule
+	for _, rule := range append(dropRules, maskRules...) {
+		if err := applyRule(data, rule, result); err != nil {
+			return nil, err
 		}
 	}
 
-	return result, nil
+	return result.build(opts, order), nil
+}
+
+// applyRule applies a single rule to the JSON data, adding what it keeps to result
+func applyRule(data interface{}, rule Rule, result *resultBuilder) error {
+	var err error
+
+	switch rule.Type {
+	case KeepPath:
+		err = applyKeepPathRule(data, rule, result)
+
+	case KeepParentIfValueMatches:
+		err = applyKeepParentIfValueMatchesRule(data, rule, result)
+
+	case KeepArrayElementsIfChildValueMatches:
+		err = applyKeepArrayElementsIfChildValueMatchesRule(data, rule, result)
+
+	case DropPath:
+		err = applyDropPathRule(data, rule, result)
+
+	case MaskPath:
+		err = applyMaskPathRule(data, rule, result)
+
+	case DropArrayElementsIfChildValueMatches:
+		err = applyDropArrayElementsIfChildValueMatchesRule(data, rule, result)
+
+	default:
+		return ErrInvalidRule
+	}
+
+	if err != nil && err != ErrPathNotFound {
+		return err
+	}
+
+	return nil
 }
 
 // applyKeepPathRule applies rule type 1: keep the specified path
-func applyKeepPathRule(data interface{}, path string, result map[string]interface{}) error {
-	// Split the path into segments
-	pathSegments := strings.Split(path, ".")
+func applyKeepPathRule(data interface{}, rule Rule, result *resultBuilder) error {
+	// Parse the path into segments
+	segments, err := rule.path()
+	if err != nil {
+		return err
+	}
 
-	// Navigate to the value
-	value, err := navigateToValue(data, pathSegments)
+	// Find every value matched by the path
+	matches, err := resolve(data, segments)
 	if err != nil {
 		return err
 	}
 
-	// Build the result structure
-	buildResultStructure(result, pathSegments, value)
+	// Add the values to the result structure
+	for _, m := range matches {
+		result.keep(m.steps, m.value)
+	}
 
 	return nil
 }
 
-// applyKeepParentIfValueMatchesRule applies rule type 2: if the value at path matches, keep the parent path
-func applyKeepParentIfValueMatchesRule(data interface{}, path string, expectedValue interface{}, result map[string]interface{}) error {
-	// Split the path into segments
-	pathSegments := strings.Split(path, ".")
+// applyKeepParentIfValueMatchesRule applies rule type 2: if the value at path matches, keep the parent path.
+// The condition is evaluated once per parent, so operators such as not_exists can see that the value is missing
+func applyKeepParentIfValueMatchesRule(data interface{}, rule Rule, result *resultBuilder) error {
+	// Parse the path into segments
+	segments, err := rule.path()
+	if err != nil {
+		return err
+	}
 
-	// Navigate to the value
-	value, err := navigateToValue(data, pathSegments)
+	// With a condition tree, path names the values to keep and the condition is evaluated against each of them
+	if rule.Condition != nil {
+		return keepIfCondition(data, rule, segments, result)
+	}
+
+	cmp, err := rule.comparison()
 	if err != nil {
 		return err
 	}
 
-	// Check if the value matches
-	if !valueEquals(value, expectedValue) {
+	// A path without segments selects the whole document, which has no parent
+	if len(segments) == 0 {
+		if cmp.match([]interface{}{data}) {
+			result.keep(nil, data)
+		}
 		return nil
 	}
 
-	// Keep the parent path (remove the last segment)
-	if len(pathSegments) > 1 {
-		parentPathSegments := pathSegments[:len(pathSegments)-1]
-		parentValue, err := navigateToValue(data, parentPathSegments)
+	// Find every parent of the values the path can match
+	parentSegments, last := segments[:len(segments)-1], segments[len(segments)-1:]
+	parents, err := resolve(data, parentSegments)
+	if err != nil {
+		return err
+	}
+
+	lenient := selectsUnnamedChildren(parentSegments)
+
+	for _, parent := range parents {
+		children, ok, err := parentChildren(data, parent, last, lenient)
 		if err != nil {
 			return err
 		}
+		if !ok {
+			continue
+		}
+
+		// If there's no parent (top-level field), keep the matched fields whose own value matches
+		if len(parent.steps) == 0 {
+			for _, child := range children {
+				if cmp.match([]interface{}{child.value}) {
+					result.keep(child.steps, child.value)
+				}
+			}
+			continue
+		}
 
-		// Build the result structure for the parent
-		buildResultStructure(result, parentPathSegments, parentValue)
-	} else {
-		// If there's no parent (top-level field), keep the whole field
-		buildResultStructure(result, pathSegments, value)
+		// Check if the values match
+		if cmp.match(matchValues(children)) {
+			// Keep the parent path
+			result.keep(parent.steps, parent.value)
+		}
 	}
 
 	return nil
 }
 
-// applyKeepArrayElementsIfChildValueMatchesRule applies rule type 3: keep array elements where child value matches
-func applyKeepArrayElementsIfChildValueMatchesRule(data interface{}, arrayPath string, childPath string, expectedValue interface{}, result map[string]interface{}) error {
-	// Split the array path into segments
-	arrayPathSegments := strings.Split(arrayPath, ".")
+// parentChildren returns the values the last segment of a rule type 2 path selects below parent.
+// ok is false when lenient is set, because the parent was reached through a wildcard or descendant
+// segment, and the parent is an array that a name does not apply to
+func parentChildren(root interface{}, parent match, last []segment, lenient bool) (children []match, ok bool, err error) {
+	children, err = resolveFrom(root, parent, last)
+	switch {
+	case err == ErrInvalidPath && lenient:
+		return nil, false, nil
+	case err != nil && err != ErrPathNotFound:
+		return nil, false, err
+	}
+	return children, true, nil
+}
 
-	// Navigate to the array
-	arrayValue, err := navigateToValue(data, arrayPathSegments)
+// matchValues returns the values of the matches
+func matchValues(matches []match) []interface{} {
+	values := make([]interface{}, 0, len(matches))
+	for _, m := range matches {
+		values = append(values, m.value)
+	}
+	return values
+}
+
+// selectsUnnamedChildren reports whether a segment selects children without naming them, so the
+// values it reaches can be objects, arrays or scalars alike
+func selectsUnnamedChildren(segments []segment) bool {
+	for _, seg := range segments {
+		switch seg.kind {
+		case wildcardSegment, descendantSegment, filterSegment, sliceSegment:
+			return true
+		case unionSegment:
+			if selectsUnnamedChildren(seg.selectors) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// applyKeepArrayElementsIfChildValueMatchesRule applies rule type 3: keep array elements where child value matches
+func applyKeepArrayElementsIfChildValueMatchesRule(data interface{}, rule Rule, result *resultBuilder) error {
+	// Parse the fields each matching element is projected onto
+	fields, err := rule.fields()
 	if err != nil {
 		return err
 	}
 
-	// Check if it's an array
-	array, ok := arrayValue.([]interface{})
-	if !ok {
-		return errors.New("path does not point to an array")
-	}
+	return selectElements(data, rule, func(m match, filtered *array) {
+		if len(fields) > 0 {
+			for i, element := range filtered.items {
+				elementMatch := match{steps: appendStep(m.steps, step{index: i, isIndex: true}), value: element}
+				filtered.items[i] = project(data, elementMatch, fields)
+			}
+		}
 
-	// Split the child path into segments
-	childPathSegments := strings.Split(childPath, ".")
+		// If we found matching elements, add them to the result structure
+		if len(filtered.items) > 0 {
+			result.keep(m.steps, filtered)
+		}
+	})
+}
 
-	// Filter the array elements
-	filteredArray := make([]interface{}, 0)
-	for _, element := range array {
-		// Try to navigate to the child value
-		childValue, err := navigateToValue(element, childPathSegments)
-		if err == nil && valueEquals(childValue, expectedValue) {
-			filteredArray = append(filteredArray, element)
+// project cuts an element down to the values matched by the field paths, which are relative to
+// the element; an element without any of the fields is kept as an empty object
+func project(data interface{}, element match, fields [][]segment) interface{} {
+	var projected interface{}
+	for _, segments := range fields {
+		matches, err := resolveFrom(data, element, segments)
+		if err != nil {
+			continue
+		}
+		for _, fm := range matches {
+			steps := fm.steps[len(element.steps):]
+			projected = place(projected, projected != nil, element.value, steps, fm.value, ConflictUnion)
 		}
 	}
 
-	// If we found matching elements, build the result structure
-	if len(filteredArray) > 0 {
-		buildResultStructure(result, arrayPathSegments, filteredArray)
+	if projected == nil {
+		return object{}
 	}
+	return projected
+}
 
-	return nil
+// applyDropArrayElementsIfChildValueMatchesRule applies rule type 6: remove array elements where child value matches
+func applyDropArrayElementsIfChildValueMatchesRule(data interface{}, rule Rule, result *resultBuilder) error {
+	return selectElements(data, rule, func(m match, filtered *array) {
+		for i := range filtered.items {
+			result.drop(appendStep(m.steps, step{index: i, isIndex: true}))
+		}
+	})
 }
 
-// navigateToValue traverses the JSON structure following the path segments
-func navigateToValue(data interface{}, pathSegments []string) (interface{}, error) {
-	if len(pathSegments) == 0 {
-		return data, nil
+// selectElements calls fn for every array matched by the rule's path with the elements that
+// satisfy the rule's element condition, kept at their original positions
+func selectElements(data interface{}, rule Rule, fn func(match, *array)) error {
+	// Parse the array path into segments
+	arraySegments, err := rule.path()
+	if err != nil {
+		return err
 	}
 
-	currentSegment := pathSegments[0]
-	remaining := pathSegments[1:]
+	// Find every array matched by the path
+	matches, err := resolve(data, arraySegments)
+	if err != nil {
+		return err
+	}
 
-	switch v := data.(type) {
-	case map[string]interface{}:
-		val, ok := v[currentSegment]
+	cond, err := rule.elementCondition()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range matches {
+		// Check if it's an array
+		array, ok := m.value.([]interface{})
 		if !ok {
-			return nil, ErrPathNotFound
+			return errNotArray
 		}
-		return navigateToValue(val, remaining)
 
-	case []interface{}:
-		// Try to parse the segment as an array index
-		index := 0
-		for i, c := range currentSegment {
-			if i == 0 && c == '-' {
-				continue
-			}
-			if c < '0' || c > '9' {
-				return nil, ErrInvalidPath
+		// Filter the array elements, remembering their original positions
+		filtered := newArray(len(array))
+		for i, element := range array {
+			elementMatch := match{steps: appendStep(m.steps, step{index: i, isIndex: true}), value: element}
+			if cond.test(data, elementMatch) {
+				filtered.items[i] = element
 			}
-			index = index*10 + int(c-'0')
 		}
 
-		// Handle negative index (count from the end)
-		if currentSegment[0] == '-' {
-			index = -index
-		}
+		fn(m, filtered)
+	}
 
-		// Convert negative index to positive
-		if index < 0 {
-			index = len(v) + index
-		}
+	return nil
+}
 
-		if index < 0 || index >= len(v) {
-			return nil, ErrPathNotFound
-		}
+// applyDropPathRule applies rule type 4: remove the specified path from the result
+func applyDropPathRule(data interface{}, rule Rule, result *resultBuilder) error {
+	// Parse the path into segments
+	segments, err := rule.path()
+	if err != nil {
+		return err
+	}
 
-		return navigateToValue(v[index], remaining)
+	// Find every value matched by the path
+	matches, err := resolve(data, segments)
+	if err != nil {
+		return err
+	}
 
-	default:
-		return nil, ErrPathNotFound
+	// Remove the values from the result structure
+	for _, m := range matches {
+		result.drop(m.steps)
 	}
+
+	return nil
 }
 
-// buildResultStructure builds a nested structure in the result map based on path segments
-func buildResultStructure(result map[string]interface{}, pathSegments []string, value interface{}) {
-	if len(pathSegments) == 0 {
-		return
+// applyMaskPathRule applies rule type 5: replace the values at the specified path that are in the result
+func applyMaskPathRule(data interface{}, rule Rule, result *resultBuilder) error {
+	// Parse the path into segments
+	segments, err := rule.path()
+	if err != nil {
+		return err
 	}
 
-	current := result
-	for i, segment := range pathSegments {
-		if i == len(pathSegments)-1 {
-			// Last segment, set the value
-			current[segment] = value
-		} else {
-			// Create intermediate objects if needed
-			if _, exists := current[segment]; !exists {
-				// Check if the next segment is a number (array index)
-				isNextSegmentArrayIndex := false
-				if i+1 < len(pathSegments) {
-					nextSegment := pathSegments[i+1]
-					isNextSegmentArrayIndex = isNumeric(nextSegment)
-				}
-
-				if isNextSegmentArrayIndex {
-					current[segment] = make([]interface{}, 0)
-				} else {
-					current[segment] = make(map[string]interface{})
-				}
-			}
+	// Find every value matched by the path
+	matches, err := resolve(data, segments)
+	if err != nil {
+		return err
+	}
 
-			// Move to the next level
-			if nextMap, ok := current[segment].(map[string]interface{}); ok {
-				current = nextMap
-			} else {
-				// Handle array case
-				// This is a simplified implementation and might need enhancement for complex cases
-				break
-			}
+	// Replace the values kept in the result structure
+	for _, m := range matches {
+		masked, err := rule.Mask.apply(m.value)
+		if err != nil {
+			return err
 		}
+		result.replace(m.steps, masked)
 	}
+
+	return nil
 }
 
-// isNumeric checks if a string represents a numeric value
-func isNumeric(s string) bool {
-	if len(s) == 0 {
-		return false
+// keepIfCondition keeps every value matched by segments that satisfies the rule's condition tree
+func keepIfCondition(data interface{}, rule Rule, segments []segment, result *resultBuilder) error {
+	cond, err := rule.condition()
+	if err != nil {
+		return err
+	}
+
+	matches, err := resolve(data, segments)
+	if err != nil {
+		return err
 	}
 
-	// Check for negative sign
-	start := 0
-	if s[0] == '-' {
-		if len(s) == 1 {
-			return false
+	for _, m := range matches {
+		if cond.test(data, m) {
+			result.keep(m.steps, m.value)
 		}
-		start = 1
 	}
 
-	// Check if all remaining characters are digits
-	for i := start; i < len(s); i++ {
-		if s[i] < '0' || s[i] > '9' {
-			return false
+	return nil
+}
+
+// condition returns the rule's compiled condition tree, compiling it now if the rule was not loaded from a config
+func (r Rule) condition() (*condition, error) {
+	if r.compiledCondition != nil {
+		return r.compiledCondition, nil
+	}
+	return compileCondition(*r.Condition, r.PathSyntax)
+}
+
+// elementCondition returns the condition array elements are tested against: the condition tree
+// if the rule has one, otherwise a leaf comparing the values at the child path
+func (r Rule) elementCondition() (*condition, error) {
+	if r.compiledElements != nil {
+		return r.compiledElements, nil
+	}
+	if r.Condition != nil {
+		return r.condition()
+	}
+
+	// Parse the child path into segments
+	childSegments, err := parseRulePath(r.ChildPath, r.PathSyntax)
+	if err != nil {
+		return nil, err
+	}
+
+	cmp, err := r.comparison()
+	if err != nil {
+		return nil, err
+	}
+
+	return &condition{segments: childSegments, cmp: cmp}, nil
+}
+
+// path returns the rule's path parsed into segments, parsing it now if the rule was not compiled
+func (r Rule) path() ([]segment, error) {
+	if r.compiledPath != nil {
+		return r.compiledPath, nil
+	}
+	return parseRulePath(r.Path, r.PathSyntax)
+}
+
+// fields returns the rule's Fields parsed into segments, parsing them now if the rule was not compiled
+func (r Rule) fields() ([][]segment, error) {
+	if r.compiledFields != nil {
+		return r.compiledFields, nil
+	}
+
+	fields := make([][]segment, 0, len(r.Fields))
+	for _, field := range r.Fields {
+		segments, err := parseRulePath(field, r.PathSyntax)
+		if err != nil {
+			return nil, err
 		}
+		fields = append(fields, segments)
 	}
+	return fields, nil
+}
 
-	return true
+// comparison returns the rule's compiled comparison, compiling it now if the rule was not loaded from a config
+func (r Rule) comparison() (*comparison, error) {
+	if r.compiled != nil {
+		return r.compiled, nil
+	}
+	return newComparison(r.Op, r.Value, r.IgnoreCase)
 }
 
-// valueEquals checks if two values are equal
-func valueEquals(a, b interface{}) bool {
-	return reflect.DeepEqual(a, b)
+// childValues returns every value matched by the path segments below node, a value inside root
+func childValues(root interface{}, node match, segments []segment) []interface{} {
+	matches, err := resolveFrom(root, node, segments)
+	if err != nil {
+		return nil
+	}
+
+	values := make([]interface{}, 0, len(matches))
+	for _, m := range matches {
+		values = append(values, m.value)
+	}
+	return values
 }
 
-// Cut extracts a portion of a JSON object based on the given path (for backward compatibility)
+// Cut extracts a portion of a JSON object based on the given path (for backward compatibility).
+// The selected values are copied from jsonData byte for byte, keeping their formatting, number
+// literals and string escapes
 func Cut(jsonData []byte, path string) ([]byte, error) {
 	rules := []Rule{NewKeepPathRule(path)}
-	return CutWithRules(jsonData, rules)
+	return cutKept(jsonData, rules)
 }
 
-// CutMultiple extracts multiple portions of a JSON object based on the given paths (for backward compatibility)
-func CutMultiple(jsonData []byte, paths []string) (map[string]json.RawMessage, error) {
-	result := make(map[string]json.RawMessage)
+// CutMultiple extracts multiple portions of a JSON object based on the given paths. The document is
+// validated and scanned once for all paths, and like Cut the selected values are copied from jsonData
+// byte for byte. Every path that could not be cut has an entry in the returned error map: ErrPathNotFound
+// if it matches nothing, with an empty object as its result, or the error that makes the path invalid,
+// with a nil result. The returned error is only set if jsonData is not valid JSON
+func CutMultiple(jsonData []byte, paths []string) (map[string]json.RawMessage, map[string]error, error) {
+	doc, err := parseRawDocument(jsonData)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results := make(map[string]json.RawMessage, len(paths))
+	errs := make(map[string]error)
 
 	for _, path := range paths {
-		rules := []Rule{NewKeepPathRule(path)}
-		cut, err := CutWithRules(jsonData, rules)
+		result := doc.newResult()
+		if err := doc.keep(result, NewKeepPathRule(path)); err != nil {
+			errs[path] = err
+			if err != ErrPathNotFound {
+				results[path] = nil
+				continue
+			}
+		}
+
+		output, err := result.output(Options{})
 		if err != nil {
-			result[path] = nil
-			continue
+			return nil, nil, err
 		}
-		result[path] = cut
+		results[path] = output
 	}
 
-	return result, nil
+	return results, errs, nil
+}
+
+// cutKept cuts jsonData with keep path rules, copying the selected values from the input unless
+// the rules need the decoded document
+func cutKept(jsonData []byte, rules []Rule) ([]byte, error) {
+	if output, ok, err := cutRaw(jsonData, rules, Options{}); ok {
+		return output, err
+	}
+	return CutWithRules(jsonData, rules)
 }
@@ -0,0 +1,94 @@
+package cutjson
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Cutter is a rule set that has been validated and parsed once, so it can be applied to any number
+// of documents without repeating that work. A Cutter is never modified after Compile returns it
+// and is safe for concurrent use by multiple goroutines
+type Cutter struct {
+	rules []Rule
+	opts  Options
+}
+
+// Compile validates the rules and parses every path, comparison and pattern in them once
+func Compile(rules []Rule) (*Cutter, error) {
+	return CompileWithOptions(rules, Options{})
+}
+
+// CompileWithOptions is like Compile, and the Cutter lays out every result according to opts
+func CompileWithOptions(rules []Rule, opts Options) (*Cutter, error) {
+	if _, err := json.Marshal(opts.Placeholder); err != nil {
+		return nil, err
+	}
+
+	compiled := make([]Rule, 0, len(rules))
+	for _, rule := range rules {
+		rule, err := compileRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, rule)
+	}
+
+	return &Cutter{rules: compiled, opts: opts}, nil
+}
+
+// Cut cuts a JSON document with the compiled rules, like CutWithOptions
+func (c *Cutter) Cut(jsonData []byte) ([]byte, error) {
+	return CutWithOptions(jsonData, c.rules, c.opts)
+}
+
+// CutReader cuts the JSON document read from r and writes the result to w by calling the
+// package-level CutReader with the compiled rules and options
+func (c *Cutter) CutReader(r io.Reader, w io.Writer) error {
+	return CutReader(r, w, c.rules, c.opts)
+}
+
+// compileRule returns a copy of rule with its path, comparison, condition and fields compiled,
+// or the error the rule would fail every cut with
+func compileRule(rule Rule) (Rule, error) {
+	segments, err := rule.path()
+	if err != nil {
+		return Rule{}, err
+	}
+	if segments == nil {
+		// A path selecting the whole document has no segments but is still compiled
+		segments = []segment{}
+	}
+	rule.compiledPath = segments
+
+	switch rule.Type {
+	case KeepPath, DropPath:
+
+	case MaskPath:
+		if _, err := rule.Mask.apply(""); err != nil {
+			return Rule{}, err
+		}
+
+	case KeepParentIfValueMatches:
+		if rule.Condition != nil {
+			rule.compiledCondition, err = rule.condition()
+		} else {
+			rule.compiled, err = rule.comparison()
+		}
+
+	case KeepArrayElementsIfChildValueMatches, DropArrayElementsIfChildValueMatches:
+		if rule.compiledElements, err = rule.elementCondition(); err != nil {
+			break
+		}
+		if len(rule.Fields) > 0 {
+			rule.compiledFields, err = rule.fields()
+		}
+
+	default:
+		return Rule{}, ErrInvalidRule
+	}
+
+	if err != nil {
+		return Rule{}, err
+	}
+	return rule, nil
+}
@@ -0,0 +1,130 @@
+package cutjson
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCutter(t *testing.T) {
+	jsonData := []byte(`{
+		"user": {"name": "John Doe", "email": "john@example.com"},
+		"orders": [
+			{"id": 1, "status": "paid", "items": [{"sku": "A", "qty": 1}], "card": "4111111111111111"},
+			{"id": 2, "status": "cancelled", "items": [], "card": "5500"},
+			{"id": 3, "status": "paid", "items": [{"sku": "C", "qty": 3}]}
+		]
+	}`)
+
+	paid := NewKeepArrayElementsIfChildValueMatchesRule("orders", "status", "paid")
+	paid.Fields = []string{"id", "card"}
+	rules := []Rule{
+		paid,
+		NewKeepParentIfValueComparesRule("user.email", OpRegex, `@example\.com$`),
+		NewMaskPathRule("orders.*.card", Mask{Mode: MaskKeepLast, KeepLast: 4}),
+		NewDropPathRule("$.orders[?@.id > 2]"),
+	}
+
+	Convey("测试编译后的规则集", t, func() {
+		Convey("结果与CutWithOptions一致", func() {
+			for _, opts := range []Options{{}, {ArrayMode: PreserveArrayPositions}, {PreserveKeyOrder: true}, {KeepAll: true}} {
+				cutter, err := CompileWithOptions(rules, opts)
+				So(err, ShouldBeNil)
+
+				result, err := cutter.Cut(jsonData)
+				So(err, ShouldBeNil)
+				expected, err := CutWithOptions(jsonData, rules, opts)
+				So(err, ShouldBeNil)
+				So(string(result), ShouldEqual, string(expected))
+			}
+
+			cutter, err := Compile(rules)
+			So(err, ShouldBeNil)
+			result, err := cutter.Cut(jsonData)
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual,
+				`{"orders":[{"card":"************1111","id":1}],"user":{"email":"john@example.com","name":"John Doe"}}`)
+
+			var out bytes.Buffer
+			So(cutter.CutReader(bytes.NewReader(jsonData), &out), ShouldBeNil)
+			So(out.String(), ShouldEqual, `{"user":{"name":"John Doe","email":"john@example.com"},"orders":[{"id":1,"card":"************1111"}]}`)
+		})
+
+		Convey("编译时预先解析路径和条件", func() {
+			cutter, err := Compile(rules)
+			So(err, ShouldBeNil)
+			for _, rule := range cutter.rules {
+				So(rule.compiledPath, ShouldNotBeNil)
+			}
+			So(cutter.rules[0].compiledElements, ShouldNotBeNil)
+			So(cutter.rules[0].compiledFields, ShouldHaveLength, 2)
+			So(cutter.rules[1].compiled, ShouldNotBeNil)
+
+			// The rules passed in are left untouched
+			So(rules[0].compiledPath, ShouldBeNil)
+
+			cutter, err = Compile([]Rule{NewKeepPathRule("$")})
+			So(err, ShouldBeNil)
+			result, err := cutter.Cut([]byte(`{"a":1}`))
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"a":1}`)
+		})
+
+		Convey("可以被多个goroutine同时使用", func() {
+			cutter, err := Compile(rules)
+			So(err, ShouldBeNil)
+			expected, err := cutter.Cut(jsonData)
+			So(err, ShouldBeNil)
+
+			var wg sync.WaitGroup
+			results := make([]string, 32)
+			for i := range results {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					for n := 0; n < 20; n++ {
+						result, err := cutter.Cut(jsonData)
+						if err != nil {
+							results[i] = err.Error()
+							return
+						}
+						results[i] = string(result)
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			for _, result := range results {
+				So(result, ShouldEqual, string(expected))
+			}
+		})
+
+		Convey("编译时报告无效的规则", func() {
+			invalid := []Rule{
+				NewKeepPathRule("orders["),
+				NewKeepParentIfValueComparesRule("user.email", OpRegex, "("),
+				NewKeepArrayElementsIfChildValueMatchesRule("orders", "items[", 1),
+				NewMaskPathRule("orders.*.card", Mask{Mode: MaskKeepLast, KeepLast: -1}),
+				{Type: RuleType(42), Path: "user"},
+			}
+			invalidFields := paid
+			invalidFields.Fields = []string{"items["}
+			invalid = append(invalid, invalidFields)
+
+			for _, rule := range invalid {
+				cutter, err := Compile([]Rule{rule})
+				So(cutter, ShouldBeNil)
+				So(err, ShouldNotBeNil)
+
+				// Cutting without compiling fails in the same way
+				_, cutErr := CutWithRules(jsonData, []Rule{rule})
+				So(cutErr, ShouldEqual, err)
+			}
+
+			_, err := CompileWithOptions(rules, Options{Placeholder: func() {}})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,180 @@
+package cutjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"sort"
+)
+
+// keyOrder records the order in which the members of each decoded object appeared in the source,
+// keyed by the identity of the object's map
+type keyOrder map[uintptr][]string
+
+// keys returns the source order of the members of m, or nil if it is unknown
+func (o keyOrder) keys(m map[string]interface{}) []string {
+	if o == nil {
+		return nil
+	}
+	return o[reflect.ValueOf(m).Pointer()]
+}
+
+// decode is json.Unmarshal with numbers decoded as json.Number, so they keep their exact source text
+func decode(jsonData []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(jsonData))
+	dec.UseNumber()
+
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+
+	// Only whitespace may follow the value
+	if _, err := dec.Token(); err != io.EOF {
+		return ErrInvalidJSON
+	}
+
+	return nil
+}
+
+// decodeOrdered is like decode into interface{} and also records the key order of every object
+func decodeOrdered(jsonData []byte) (interface{}, keyOrder, error) {
+	dec := json.NewDecoder(bytes.NewReader(jsonData))
+	dec.UseNumber()
+	order := make(keyOrder)
+
+	data, err := decodeValue(dec, order)
+	if err != nil {
+		return nil, nil, ErrInvalidJSON
+	}
+
+	// Only whitespace may follow the value
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, nil, ErrInvalidJSON
+	}
+
+	return data, order, nil
+}
+
+// decodeValue decodes the next value from dec
+func decodeValue(dec *json.Decoder, order keyOrder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		return decodeMembers(dec, order)
+
+	case json.Delim('['):
+		arr := []interface{}{}
+		for dec.More() {
+			value, err := decodeValue(dec, order)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, value)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return arr, nil
+
+	default:
+		return tok, nil
+	}
+}
+
+// decodeMembers decodes the members of an object whose opening brace has been read from dec,
+// up to and including its closing brace
+func decodeMembers(dec *json.Decoder, order keyOrder) (map[string]interface{}, error) {
+	obj := make(map[string]interface{})
+	var keys []string
+	for dec.More() {
+		keyTok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := keyTok.(string)
+
+		value, err := decodeValue(dec, order)
+		if err != nil {
+			return nil, err
+		}
+
+		// A duplicate key keeps its first position and its last value, as with json.Unmarshal
+		if _, found := obj[key]; !found {
+			keys = append(keys, key)
+		}
+		obj[key] = value
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	order[reflect.ValueOf(obj).Pointer()] = keys
+	return obj, nil
+}
+
+// isJSONNumber reports whether text is a number in JSON syntax, such as -1.5e3
+func isJSONNumber(text string) bool {
+	if text == "" || (text[0] != '-' && (text[0] < '0' || text[0] > '9')) {
+		return false
+	}
+	return json.Valid([]byte(text))
+}
+
+// orderedObject is a JSON object that is marshalled with its members in a fixed order
+type orderedObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// MarshalJSON implements json.Marshaler
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+
+		v, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// newOrderedObject orders the members of values as they appear in source, followed by any other members sorted by name
+func newOrderedObject(values map[string]interface{}, source []string) orderedObject {
+	keys := make([]string, 0, len(values))
+	seen := make(map[string]bool, len(values))
+	for _, key := range source {
+		if _, found := values[key]; found && !seen[key] {
+			keys = append(keys, key)
+			seen[key] = true
+		}
+	}
+
+	var rest []string
+	for key := range values {
+		if !seen[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+
+	return orderedObject{keys: append(keys, rest...), values: values}
+}
@@ -0,0 +1,131 @@
+package cutjson
+
+import (
+	"sort"
+	"strings"
+)
+
+// FieldMask is a set of protobuf FieldMask paths such as user.address.city. Field names may be
+// written in snake_case or lowerCamelCase; both match either spelling in the JSON document
+type FieldMask struct {
+	Paths []string `json:"paths"`
+}
+
+// ParseFieldMask parses the JSON encoding of a google.protobuf.FieldMask: paths separated by commas,
+// as in "user.displayName,products.id"
+func ParseFieldMask(mask string) (FieldMask, error) {
+	var m FieldMask
+	if strings.TrimSpace(mask) == "" {
+		return m, nil
+	}
+
+	for _, path := range strings.Split(mask, ",") {
+		path = strings.TrimSpace(path)
+		if _, err := parseFieldMaskPath(path); err != nil {
+			return FieldMask{}, err
+		}
+		m.Paths = append(m.Paths, path)
+	}
+	return m, nil
+}
+
+// String returns the JSON encoding of the mask: its paths joined by commas
+func (m FieldMask) String() string {
+	return strings.Join(m.Paths, ",")
+}
+
+// Union returns a mask selecting everything selected by either mask; paths covered by another
+// path, such as user.name next to user, are dropped and the rest sorted
+func (m FieldMask) Union(other FieldMask) FieldMask {
+	paths := append(append([]string{}, m.Paths...), other.Paths...)
+	return FieldMask{Paths: normalizeMaskPaths(paths)}
+}
+
+// Intersect returns a mask selecting only what is selected by both masks: the intersection of
+// user and user.name is user.name
+func (m FieldMask) Intersect(other FieldMask) FieldMask {
+	var paths []string
+	for _, a := range normalizeMaskPaths(m.Paths) {
+		for _, b := range normalizeMaskPaths(other.Paths) {
+			switch {
+			case coversMaskPath(a, b):
+				paths = append(paths, b)
+			case coversMaskPath(b, a):
+				paths = append(paths, a)
+			}
+		}
+	}
+	return FieldMask{Paths: normalizeMaskPaths(paths)}
+}
+
+// Rules converts the mask into KeepPath rules, one per path
+func (m FieldMask) Rules() ([]Rule, error) {
+	rules := make([]Rule, 0, len(m.Paths))
+	for _, path := range m.Paths {
+		if _, err := parseFieldMaskPath(path); err != nil {
+			return nil, err
+		}
+		rule := NewKeepPathRule(path)
+		rule.PathSyntax = PathFieldMask
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// CutFieldMask cuts a JSON object down to the fields selected by a protobuf FieldMask;
+// an empty mask selects nothing
+func CutFieldMask(jsonData []byte, mask FieldMask) ([]byte, error) {
+	rules, err := mask.Rules()
+	if err != nil {
+		return nil, err
+	}
+	return CutWithRules(jsonData, rules)
+}
+
+// parseFieldMaskPath parses a FieldMask path such as user.display_name into segments
+func parseFieldMaskPath(path string) ([]segment, error) {
+	parts := strings.Split(path, ".")
+	segments := make([]segment, 0, len(parts))
+
+	for _, part := range parts {
+		if part == "" {
+			return nil, ErrInvalidPath
+		}
+		segments = append(segments, segment{kind: maskFieldSegment, name: part})
+	}
+
+	return segments, nil
+}
+
+// normalizeMaskPaths sorts paths by their folded spelling and removes duplicates and paths covered by another path
+func normalizeMaskPaths(paths []string) []string {
+	sorted := append([]string{}, paths...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return foldFieldName(sorted[i]) < foldFieldName(sorted[j])
+	})
+
+	var out []string
+	for _, path := range sorted {
+		// A covering path sorts before the paths it covers, but names such as a-b may come in between
+		if !coveredMaskPath(out, path) {
+			out = append(out, path)
+		}
+	}
+	return out
+}
+
+// coveredMaskPath reports whether one of paths covers path
+func coveredMaskPath(paths []string, path string) bool {
+	for _, p := range paths {
+		if coversMaskPath(p, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// coversMaskPath reports whether path a selects everything path b selects: a equals b or is a prefix of it
+func coversMaskPath(a, b string) bool {
+	fa, fb := foldFieldName(a), foldFieldName(b)
+	return fa == fb || strings.HasPrefix(fb, fa+".")
+}
@@ -0,0 +1,66 @@
+package cutjson
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFieldMask(t *testing.T) {
+	jsonData := []byte(`{
+		"displayName": "John Doe",
+		"user": {"address": {"city": "New York", "zip_code": "10001"}, "age": 30},
+		"products": [
+			{"productId": 101, "name": "Laptop"},
+			{"productId": 102, "name": "Mouse"}
+		]
+	}`)
+
+	Convey("测试protobuf FieldMask", t, func() {
+		Convey("snake_case和camelCase互相匹配", func() {
+			mask, err := ParseFieldMask("display_name, user.address.zipCode, products.product_id")
+			So(err, ShouldBeNil)
+			result, err := CutFieldMask(jsonData, mask)
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual,
+				`{"displayName":"John Doe","products":[{"productId":101},{"productId":102}],"user":{"address":{"zip_code":"10001"}}}`)
+		})
+
+		Convey("精确的字段名优先", func() {
+			data := []byte(`{"user_id": 1, "userId": 2}`)
+			result, err := CutFieldMask(data, FieldMask{Paths: []string{"userId"}})
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"userId":2}`)
+		})
+
+		Convey("并集去掉被覆盖的路径", func() {
+			a := FieldMask{Paths: []string{"user.address.city", "products"}}
+			b := FieldMask{Paths: []string{"user.address", "display_name", "products.name"}}
+			So(a.Union(b).Paths, ShouldResemble, []string{"display_name", "products", "user.address"})
+
+			// Names sorting before the dot may come between a path and the paths it covers
+			So(normalizeMaskPaths([]string{"a", "a-b", "a.c"}), ShouldResemble, []string{"a", "a-b"})
+			So(FieldMask{Paths: []string{"user.name", "user-id"}}.Union(FieldMask{Paths: []string{"user"}}).Paths,
+				ShouldResemble, []string{"user", "user-id"})
+		})
+
+		Convey("交集保留两者都选中的路径", func() {
+			a := FieldMask{Paths: []string{"user", "products.name", "displayName"}}
+			b := FieldMask{Paths: []string{"user.address.city", "user.age", "products", "tags"}}
+			So(a.Intersect(b).Paths, ShouldResemble, []string{"products.name", "user.address.city", "user.age"})
+			So(FieldMask{Paths: []string{"user_name"}}.Intersect(FieldMask{Paths: []string{"userName.first"}}).Paths,
+				ShouldResemble, []string{"userName.first"})
+		})
+
+		Convey("无效的路径", func() {
+			_, err := ParseFieldMask("user..name")
+			So(err, ShouldEqual, ErrInvalidPath)
+			_, err = ParseFieldMask("a,,b")
+			So(err, ShouldEqual, ErrInvalidPath)
+
+			mask, err := ParseFieldMask("")
+			So(err, ShouldBeNil)
+			So(mask.Paths, ShouldBeEmpty)
+		})
+	})
+}
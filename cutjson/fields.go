@@ -0,0 +1,119 @@
+package cutjson
+
+import "strings"
+
+// CutFields cuts a JSON object down to a partial-response field selector such as
+// user(name,address/city),products(id,name),orders/items/productId
+func CutFields(jsonData []byte, fields string) ([]byte, error) {
+	rules, err := ParseFieldSelector(fields)
+	if err != nil {
+		return nil, err
+	}
+	return CutWithRules(jsonData, rules)
+}
+
+// ParseFieldSelector compiles a partial-response field selector into KeepPath rules, one per
+// selected field path. Fields are separated by ',', '/' descends into a field, a(b,c) selects
+// several subfields of a and * selects every member; a field applied to an array selects it in
+// every element
+func ParseFieldSelector(fields string) ([]Rule, error) {
+	p := &fieldSelectorParser{input: fields}
+
+	paths, err := p.parseList("")
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.input) {
+		// An unmatched closing parenthesis
+		return nil, ErrInvalidPath
+	}
+
+	rules := make([]Rule, 0, len(paths))
+	for _, path := range paths {
+		rule := NewKeepPathRule(path)
+		rule.PathSyntax = PathFields
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// fieldSelectorParser expands the parentheses of a field selector into plain field paths
+type fieldSelectorParser struct {
+	input string
+	pos   int
+}
+
+// parseList parses field (',' field)* and returns the expanded paths, each prefixed with prefix
+func (p *fieldSelectorParser) parseList(prefix string) ([]string, error) {
+	var paths []string
+
+	for {
+		expanded, err := p.parseField(prefix)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, expanded...)
+
+		if p.pos >= len(p.input) || p.input[p.pos] != ',' {
+			return paths, nil
+		}
+		p.pos++
+	}
+}
+
+// parseField parses a path such as a/b/c, optionally followed by a parenthesised sub-selection
+func (p *fieldSelectorParser) parseField(prefix string) ([]string, error) {
+	start := p.pos
+	for p.pos < len(p.input) && !strings.ContainsRune(",()", rune(p.input[p.pos])) {
+		p.pos++
+	}
+
+	path := strings.TrimSpace(p.input[start:p.pos])
+	if path == "" {
+		return nil, ErrInvalidPath
+	}
+	if _, err := parseFieldPath(path); err != nil {
+		return nil, err
+	}
+	path = prefix + path
+
+	if p.pos >= len(p.input) || p.input[p.pos] != '(' {
+		return []string{path}, nil
+	}
+
+	p.pos++
+	paths, err := p.parseList(path + "/")
+	if err != nil {
+		return nil, err
+	}
+	if p.pos >= len(p.input) || p.input[p.pos] != ')' {
+		return nil, ErrInvalidPath
+	}
+	p.pos++
+
+	// Whitespace may follow the closing parenthesis
+	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+	return paths, nil
+}
+
+// parseFieldPath parses a single field path such as orders/items/productId into segments
+func parseFieldPath(path string) ([]segment, error) {
+	parts := strings.Split(path, "/")
+	segments := make([]segment, 0, len(parts))
+
+	for _, part := range parts {
+		name := strings.TrimSpace(part)
+		switch name {
+		case "":
+			return nil, ErrInvalidPath
+		case "*":
+			segments = append(segments, segment{kind: wildcardSegment})
+		default:
+			segments = append(segments, segment{kind: fieldSegment, name: name})
+		}
+	}
+
+	return segments, nil
+}
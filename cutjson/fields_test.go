@@ -0,0 +1,62 @@
+package cutjson
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFieldSelector(t *testing.T) {
+	jsonData := []byte(`{
+		"user": {"name": "John Doe", "age": 30, "address": {"city": "New York", "zip": "10001"}},
+		"products": [
+			{"id": 101, "name": "Laptop", "price": 999},
+			{"id": 102, "name": "Mouse", "price": 25}
+		],
+		"orders": [
+			{"id": 1, "items": [{"productId": 101, "qty": 1}, {"productId": 102, "qty": 2}]},
+			{"id": 2, "items": [{"productId": 102, "qty": 5}]}
+		]
+	}`)
+
+	Convey("测试部分响应字段选择器", t, func() {
+		Convey("展开括号为字段路径", func() {
+			rules, err := ParseFieldSelector("user(name,address/city),products(id,name),orders/items/productId")
+			So(err, ShouldBeNil)
+
+			paths := make([]string, 0, len(rules))
+			for _, rule := range rules {
+				So(rule.Type, ShouldEqual, KeepPath)
+				So(rule.PathSyntax, ShouldEqual, PathFields)
+				paths = append(paths, rule.Path)
+			}
+			So(paths, ShouldResemble, []string{
+				"user/name", "user/address/city", "products/id", "products/name", "orders/items/productId",
+			})
+		})
+
+		Convey("字段作用于数组时对每个元素生效", func() {
+			result, err := CutFields(jsonData, "user(name,address/city),products(id,name),orders/items/productId")
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual,
+				`{"orders":[{"items":[{"productId":101},{"productId":102}]},{"items":[{"productId":102}]}],`+
+					`"products":[{"id":101,"name":"Laptop"},{"id":102,"name":"Mouse"}],`+
+					`"user":{"address":{"city":"New York"},"name":"John Doe"}}`)
+		})
+
+		Convey("嵌套括号和通配符", func() {
+			result, err := CutFields(jsonData, "orders(id, items(qty)), user/address/*")
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual,
+				`{"orders":[{"id":1,"items":[{"qty":1},{"qty":2}]},{"id":2,"items":[{"qty":5}]}],`+
+					`"user":{"address":{"city":"New York","zip":"10001"}}}`)
+		})
+
+		Convey("无效的字段选择器", func() {
+			for _, fields := range []string{"", "user(", "user)", "user()", "a,,b", "a//b", "a(b)c"} {
+				_, err := ParseFieldSelector(fields)
+				So(err, ShouldEqual, ErrInvalidPath)
+			}
+		})
+	})
+}
@@ -0,0 +1,346 @@
+package cutjson
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// isJSONPath reports whether path looks like a JSONPath query: $ on its own or followed by a segment.
+// A relative query starting with @ is accepted as well, which is useful for child paths
+func isJSONPath(path string) bool {
+	if path == "$" || path == "@" {
+		return true
+	}
+	if len(path) < 2 || (path[0] != '$' && path[0] != '@') {
+		return false
+	}
+	return path[1] == '.' || path[1] == '['
+}
+
+// parseJSONPath parses an RFC 9535 JSONPath query such as $.store.book[?@.price < 10].title
+// into segments. The query may also start with @, which then refers to the value the path
+// is resolved against
+func parseJSONPath(path string) ([]segment, error) {
+	p := &jsonPathParser{input: path}
+
+	if !p.consume("$") && !p.consume("@") {
+		return nil, ErrInvalidPath
+	}
+
+	segments, err := p.parseSegments()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos != len(p.input) {
+		return nil, ErrInvalidPath
+	}
+
+	return segments, nil
+}
+
+// jsonPathParser holds the state of parseJSONPath; it is shared with the filter expression parser
+type jsonPathParser struct {
+	input string
+	pos   int
+}
+
+// parseSegments parses the segments following the $ or @ identifier of a query
+func (p *jsonPathParser) parseSegments() ([]segment, error) {
+	segments := []segment{}
+
+	for {
+		start := p.pos
+		p.skipSpaces()
+
+		switch {
+		case p.consume(".."):
+			segments = append(segments, segment{kind: descendantSegment})
+			var seg segment
+			var err error
+			if p.peek() == '[' {
+				seg, err = p.parseBracketedSelection()
+			} else {
+				seg, err = p.parseDotSelector()
+			}
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, seg)
+
+		case p.consume("."):
+			seg, err := p.parseDotSelector()
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, seg)
+
+		case p.peek() == '[':
+			seg, err := p.parseBracketedSelection()
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, seg)
+
+		default:
+			// Whitespace not followed by a segment belongs to the enclosing expression
+			p.pos = start
+			return segments, nil
+		}
+	}
+}
+
+// parseDotSelector parses the wildcard or member name following a '.'
+func (p *jsonPathParser) parseDotSelector() (segment, error) {
+	if p.consume("*") {
+		return segment{kind: wildcardSegment}, nil
+	}
+
+	name := p.parseMemberName()
+	if name == "" {
+		return segment{}, ErrInvalidPath
+	}
+
+	return segment{kind: nameSegment, name: name, quoted: true}, nil
+}
+
+// parseMemberName parses a member-name-shorthand: a letter, '_' or non-ASCII character
+// followed by any number of those or digits
+func (p *jsonPathParser) parseMemberName() string {
+	start := p.pos
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		isFirst := c == '_' || c >= 0x80 || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		if !isFirst && (p.pos == start || c < '0' || c > '9') {
+			break
+		}
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+// parseBracketedSelection parses '[' selector (',' selector)* ']'
+func (p *jsonPathParser) parseBracketedSelection() (segment, error) {
+	if !p.consume("[") {
+		return segment{}, ErrInvalidPath
+	}
+
+	var selectors []segment
+	for {
+		p.skipSpaces()
+		selector, err := p.parseSelector()
+		if err != nil {
+			return segment{}, err
+		}
+		selectors = append(selectors, selector)
+
+		p.skipSpaces()
+		if p.consume("]") {
+			break
+		}
+		if !p.consume(",") {
+			return segment{}, ErrInvalidPath
+		}
+	}
+
+	if len(selectors) == 1 {
+		return selectors[0], nil
+	}
+	return segment{kind: unionSegment, selectors: selectors}, nil
+}
+
+// parseSelector parses a single selector inside brackets: a name, wildcard, index, slice or filter
+func (p *jsonPathParser) parseSelector() (segment, error) {
+	switch c := p.peek(); {
+	case c == '\'' || c == '"':
+		name, err := p.parseString()
+		if err != nil {
+			return segment{}, err
+		}
+		return segment{kind: nameSegment, name: name, quoted: true}, nil
+
+	case c == '*':
+		p.pos++
+		return segment{kind: wildcardSegment}, nil
+
+	case c == '?':
+		p.pos++
+		p.skipSpaces()
+		expr, err := p.parseLogicalOr()
+		if err != nil {
+			return segment{}, err
+		}
+		return segment{kind: filterSegment, filter: expr}, nil
+
+	case c == '-' || c == ':' || (c >= '0' && c <= '9'):
+		start := p.pos
+		for p.pos < len(p.input) && isSliceChar(p.input[p.pos]) {
+			p.pos++
+		}
+		text := strings.TrimRight(p.input[start:p.pos], " \t\n\r")
+		p.pos = start + len(text)
+
+		if strings.Contains(text, ":") {
+			bounds, err := parseSlice(text, parseJSONPathInteger)
+			if err != nil {
+				return segment{}, err
+			}
+			return segment{kind: sliceSegment, slice: bounds}, nil
+		}
+
+		index, err := parseJSONPathInteger(text)
+		if err != nil {
+			return segment{}, err
+		}
+		return segment{kind: indexSegment, index: index}, nil
+
+	default:
+		return segment{}, ErrInvalidPath
+	}
+}
+
+func isSliceChar(c byte) bool {
+	return c == '-' || c == ':' || c == ' ' || c == '\t' || c == '\n' || c == '\r' || (c >= '0' && c <= '9')
+}
+
+// parseJSONPathInteger parses an optionally negative decimal integer without leading zeros
+func parseJSONPathInteger(text string) (int, error) {
+	digits := strings.TrimPrefix(text, "-")
+	if digits == "" || text == "-0" || (len(digits) > 1 && digits[0] == '0') {
+		return 0, ErrInvalidPath
+	}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return 0, ErrInvalidPath
+		}
+	}
+
+	n, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, ErrInvalidPath
+	}
+	return n, nil
+}
+
+// parseString parses a single- or double-quoted string literal with JSON-style escapes
+func (p *jsonPathParser) parseString() (string, error) {
+	quote := p.input[p.pos]
+	p.pos++
+
+	var s strings.Builder
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return s.String(), nil
+
+		case c == '\\':
+			if p.pos+1 >= len(p.input) {
+				return "", ErrInvalidPath
+			}
+			p.pos++
+			switch e := p.input[p.pos]; e {
+			case 'b':
+				s.WriteByte('\b')
+			case 'f':
+				s.WriteByte('\f')
+			case 'n':
+				s.WriteByte('\n')
+			case 'r':
+				s.WriteByte('\r')
+			case 't':
+				s.WriteByte('\t')
+			case '/', '\\', '\'', '"':
+				s.WriteByte(e)
+			case 'u':
+				r, err := p.parseUnicodeEscape()
+				if err != nil {
+					return "", err
+				}
+				s.WriteRune(r)
+				continue
+			default:
+				return "", ErrInvalidPath
+			}
+			p.pos++
+
+		case c < 0x20:
+			return "", ErrInvalidPath
+
+		default:
+			s.WriteByte(c)
+			p.pos++
+		}
+	}
+
+	return "", ErrInvalidPath
+}
+
+// parseUnicodeEscape parses the XXXX of a \uXXXX escape, combining surrogate pairs;
+// p.pos points at the 'u' and is left after the escape
+func (p *jsonPathParser) parseUnicodeEscape() (rune, error) {
+	r, err := p.parseHex4()
+	if err != nil {
+		return 0, err
+	}
+
+	if utf16.IsSurrogate(r) {
+		if !p.consume(`\u`) {
+			return 0, ErrInvalidPath
+		}
+		p.pos-- // parseHex4 expects to start at the 'u'
+		low, err := p.parseHex4()
+		if err != nil {
+			return 0, err
+		}
+		r = utf16.DecodeRune(r, low)
+		if r == utf8.RuneError {
+			return 0, ErrInvalidPath
+		}
+	}
+
+	return r, nil
+}
+
+// parseHex4 parses 'u' followed by four hex digits
+func (p *jsonPathParser) parseHex4() (rune, error) {
+	if p.pos+5 > len(p.input) {
+		return 0, ErrInvalidPath
+	}
+	n, err := strconv.ParseUint(p.input[p.pos+1:p.pos+5], 16, 32)
+	if err != nil {
+		return 0, ErrInvalidPath
+	}
+	p.pos += 5
+	return rune(n), nil
+}
+
+func (p *jsonPathParser) peek() byte {
+	if p.pos < len(p.input) {
+		return p.input[p.pos]
+	}
+	return 0
+}
+
+// consume advances past s if the input continues with it
+func (p *jsonPathParser) consume(s string) bool {
+	if strings.HasPrefix(p.input[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *jsonPathParser) skipSpaces() {
+	for p.pos < len(p.input) {
+		switch p.input[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
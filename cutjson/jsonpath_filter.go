@@ -0,0 +1,513 @@
+package cutjson
+
+import (
+	"encoding/json"
+	"regexp"
+	"unicode/utf8"
+)
+
+// filterContext is what a JSONPath filter expression is evaluated against:
+// root is the value $ refers to and current the value @ refers to
+type filterContext struct {
+	root    interface{}
+	current interface{}
+}
+
+// logicalExpr is a JSONPath filter expression producing true or false
+type logicalExpr interface {
+	test(ctx *filterContext) bool
+}
+
+// valueExpr is a JSONPath filter operand producing a single JSON value, or nothing
+type valueExpr interface {
+	value(ctx *filterContext) (interface{}, bool)
+}
+
+// orExpr is a || b
+type orExpr struct{ left, right logicalExpr }
+
+func (e *orExpr) test(ctx *filterContext) bool { return e.left.test(ctx) || e.right.test(ctx) }
+
+// andExpr is a && b
+type andExpr struct{ left, right logicalExpr }
+
+func (e *andExpr) test(ctx *filterContext) bool { return e.left.test(ctx) && e.right.test(ctx) }
+
+// notExpr is !a
+type notExpr struct{ expr logicalExpr }
+
+func (e *notExpr) test(ctx *filterContext) bool { return !e.expr.test(ctx) }
+
+// existsExpr holds when its query selects at least one value
+type existsExpr struct{ query *queryExpr }
+
+func (e *existsExpr) test(ctx *filterContext) bool { return len(e.query.nodes(ctx)) > 0 }
+
+// comparisonExpr compares two operands following RFC 9535 section 2.3.5.2.2
+type comparisonExpr struct {
+	op          string
+	left, right valueExpr
+}
+
+func (e *comparisonExpr) test(ctx *filterContext) bool {
+	left, leftOK := e.left.value(ctx)
+	right, rightOK := e.right.value(ctx)
+
+	switch e.op {
+	case "==":
+		return filterEquals(left, leftOK, right, rightOK)
+	case "!=":
+		return !filterEquals(left, leftOK, right, rightOK)
+	case "<":
+		return leftOK && rightOK && filterLess(left, right)
+	case ">":
+		return leftOK && rightOK && filterLess(right, left)
+	case "<=":
+		return filterEquals(left, leftOK, right, rightOK) || (leftOK && rightOK && filterLess(left, right))
+	case ">=":
+		return filterEquals(left, leftOK, right, rightOK) || (leftOK && rightOK && filterLess(right, left))
+	default:
+		return false
+	}
+}
+
+// filterEquals compares two operands; two missing operands are equal to each other
+func filterEquals(left interface{}, leftOK bool, right interface{}, rightOK bool) bool {
+	if !leftOK || !rightOK {
+		return leftOK == rightOK
+	}
+	return valueEquals(left, right)
+}
+
+// filterLess orders two numbers or two strings; any other combination is unordered
+func filterLess(left, right interface{}) bool {
+	order, ok := compareValues(left, right)
+	return ok && order < 0
+}
+
+// literalExpr is a JSON literal in a filter expression
+type literalExpr struct{ val interface{} }
+
+func (e *literalExpr) value(*filterContext) (interface{}, bool) { return e.val, true }
+
+// queryExpr is an absolute ($) or relative (@) query inside a filter expression
+type queryExpr struct {
+	absolute bool
+	segments []segment
+}
+
+// nodes returns every value selected by the query
+func (e *queryExpr) nodes(ctx *filterContext) []interface{} {
+	start := ctx.current
+	if e.absolute {
+		start = ctx.root
+	}
+
+	var values []interface{}
+	w := &pathWalker{root: ctx.root, visit: func(_ []step, value interface{}) {
+		values = append(values, value)
+	}}
+	if err := w.walk(start, e.segments, nil); err != nil {
+		return nil
+	}
+	return values
+}
+
+// value returns the single value selected by a singular query
+func (e *queryExpr) value(ctx *filterContext) (interface{}, bool) {
+	values := e.nodes(ctx)
+	if len(values) != 1 {
+		return nil, false
+	}
+	return values[0], true
+}
+
+// isSingular reports whether the query can select at most one value
+func (e *queryExpr) isSingular() bool {
+	for _, seg := range e.segments {
+		if seg.kind != nameSegment && seg.kind != indexSegment {
+			return false
+		}
+	}
+	return true
+}
+
+// functionExpr is a call of one of the RFC 9535 function extensions
+type functionExpr struct {
+	name string
+	args []interface{} // *queryExpr, valueExpr or logicalExpr
+	re   *regexp.Regexp
+}
+
+// value evaluates length(), count() and value()
+func (e *functionExpr) value(ctx *filterContext) (interface{}, bool) {
+	switch e.name {
+	case "length":
+		v, ok := argValue(e.args[0], ctx)
+		if !ok {
+			return nil, false
+		}
+		switch x := v.(type) {
+		case string:
+			return float64(utf8.RuneCountInString(x)), true
+		case []interface{}:
+			return float64(len(x)), true
+		case map[string]interface{}:
+			return float64(len(x)), true
+		default:
+			return nil, false
+		}
+
+	case "count":
+		return float64(len(e.args[0].(*queryExpr).nodes(ctx))), true
+
+	case "value":
+		return e.args[0].(*queryExpr).value(ctx)
+
+	default:
+		return nil, false
+	}
+}
+
+// test evaluates match() and search()
+func (e *functionExpr) test(ctx *filterContext) bool {
+	v, ok := argValue(e.args[0], ctx)
+	if !ok {
+		return false
+	}
+	s, ok := v.(string)
+	if !ok {
+		return false
+	}
+
+	re := e.re
+	if re == nil {
+		// The pattern is not a literal, so it has to be compiled for every evaluation
+		pattern, ok := argValue(e.args[1], ctx)
+		if !ok {
+			return false
+		}
+		p, ok := pattern.(string)
+		if !ok {
+			return false
+		}
+		compiled, err := compileIRegexp(p, e.name == "match")
+		if err != nil {
+			return false
+		}
+		re = compiled
+	}
+
+	return re.MatchString(s)
+}
+
+// argValue evaluates a function argument that is expected to produce a single value
+func argValue(arg interface{}, ctx *filterContext) (interface{}, bool) {
+	if v, ok := arg.(valueExpr); ok {
+		return v.value(ctx)
+	}
+	return nil, false
+}
+
+// compileIRegexp compiles an I-Regexp (RFC 9485); match() anchors it at both ends
+func compileIRegexp(pattern string, anchored bool) (*regexp.Regexp, error) {
+	if anchored {
+		pattern = `\A(?:` + pattern + `)\z`
+	}
+	return regexp.Compile(pattern)
+}
+
+// parseLogicalOr parses logical-or-expr = logical-and-expr *("||" logical-and-expr)
+func (p *jsonPathParser) parseLogicalOr() (logicalExpr, error) {
+	left, err := p.parseLogicalAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		p.skipSpaces()
+		if !p.consume("||") {
+			return left, nil
+		}
+		p.skipSpaces()
+		right, err := p.parseLogicalAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orExpr{left: left, right: right}
+	}
+}
+
+// parseLogicalAnd parses logical-and-expr = basic-expr *("&&" basic-expr)
+func (p *jsonPathParser) parseLogicalAnd() (logicalExpr, error) {
+	left, err := p.parseBasic()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		p.skipSpaces()
+		if !p.consume("&&") {
+			return left, nil
+		}
+		p.skipSpaces()
+		right, err := p.parseBasic()
+		if err != nil {
+			return nil, err
+		}
+		left = &andExpr{left: left, right: right}
+	}
+}
+
+// parseBasic parses a parenthesized expression, a comparison or a test expression, optionally negated
+func (p *jsonPathParser) parseBasic() (logicalExpr, error) {
+	if p.consume("!") {
+		p.skipSpaces()
+		expr, err := p.parseNegatable()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpr{expr: expr}, nil
+	}
+
+	if p.peek() == '(' {
+		return p.parseNegatable()
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	start := p.pos
+	p.skipSpaces()
+	op := p.parseComparisonOp()
+	if op == "" {
+		p.pos = start
+		return asTest(left)
+	}
+
+	p.skipSpaces()
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	leftValue, err := asComparable(left)
+	if err != nil {
+		return nil, err
+	}
+	rightValue, err := asComparable(right)
+	if err != nil {
+		return nil, err
+	}
+
+	return &comparisonExpr{op: op, left: leftValue, right: rightValue}, nil
+}
+
+// parseNegatable parses what may follow '!': a parenthesized expression or a test expression
+func (p *jsonPathParser) parseNegatable() (logicalExpr, error) {
+	if p.consume("(") {
+		p.skipSpaces()
+		expr, err := p.parseLogicalOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		if !p.consume(")") {
+			return nil, ErrInvalidPath
+		}
+		return expr, nil
+	}
+
+	operand, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return asTest(operand)
+}
+
+// parseComparisonOp parses one of == != <= >= < >, returning "" if none follows
+func (p *jsonPathParser) parseComparisonOp() string {
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consume(op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// parseOperand parses a query, a function call or a literal
+func (p *jsonPathParser) parseOperand() (interface{}, error) {
+	switch c := p.peek(); {
+	case c == '$' || c == '@':
+		p.pos++
+		segments, err := p.parseSegments()
+		if err != nil {
+			return nil, err
+		}
+		return &queryExpr{absolute: c == '$', segments: segments}, nil
+
+	case c == '\'' || c == '"':
+		s, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return &literalExpr{val: s}, nil
+
+	case c == '-' || (c >= '0' && c <= '9'):
+		return p.parseNumber()
+
+	case c >= 'a' && c <= 'z':
+		start := p.pos
+		for p.pos < len(p.input) && (isFunctionNameChar(p.input[p.pos])) {
+			p.pos++
+		}
+		name := p.input[start:p.pos]
+
+		switch name {
+		case "true":
+			return &literalExpr{val: true}, nil
+		case "false":
+			return &literalExpr{val: false}, nil
+		case "null":
+			return &literalExpr{val: nil}, nil
+		}
+
+		if !p.consume("(") {
+			return nil, ErrInvalidPath
+		}
+		return p.parseFunction(name)
+
+	default:
+		return nil, ErrInvalidPath
+	}
+}
+
+func isFunctionNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9')
+}
+
+// parseNumber parses a JSON number literal
+func (p *jsonPathParser) parseNumber() (interface{}, error) {
+	start := p.pos
+	p.consume("-")
+	for p.pos < len(p.input) && isNumberChar(p.input[p.pos]) {
+		p.pos++
+	}
+
+	text := p.input[start:p.pos]
+	if !isJSONNumber(text) {
+		return nil, ErrInvalidPath
+	}
+	return &literalExpr{val: json.Number(text)}, nil
+}
+
+func isNumberChar(c byte) bool {
+	return (c >= '0' && c <= '9') || c == '.' || c == 'e' || c == 'E' || c == '+' || c == '-'
+}
+
+// parseFunction parses the arguments of a function call and checks them against the function's signature
+func (p *jsonPathParser) parseFunction(name string) (interface{}, error) {
+	var args []interface{}
+
+	p.skipSpaces()
+	if !p.consume(")") {
+		for {
+			p.skipSpaces()
+			arg, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+
+			p.skipSpaces()
+			if p.consume(")") {
+				break
+			}
+			if !p.consume(",") {
+				return nil, ErrInvalidPath
+			}
+		}
+	}
+
+	fn := &functionExpr{name: name, args: args}
+
+	switch name {
+	case "length":
+		if len(args) != 1 {
+			return nil, ErrInvalidPath
+		}
+		if _, err := asComparable(args[0]); err != nil {
+			return nil, err
+		}
+
+	case "count", "value":
+		if len(args) != 1 {
+			return nil, ErrInvalidPath
+		}
+		if _, ok := args[0].(*queryExpr); !ok {
+			return nil, ErrInvalidPath
+		}
+
+	case "match", "search":
+		if len(args) != 2 {
+			return nil, ErrInvalidPath
+		}
+		for _, arg := range args {
+			if _, err := asComparable(arg); err != nil {
+				return nil, err
+			}
+		}
+		// Compile literal patterns once, when the path is parsed
+		if literal, ok := args[1].(*literalExpr); ok {
+			pattern, ok := literal.val.(string)
+			if !ok {
+				return nil, ErrInvalidPath
+			}
+			re, err := compileIRegexp(pattern, name == "match")
+			if err != nil {
+				return nil, ErrInvalidPath
+			}
+			fn.re = re
+		}
+
+	default:
+		return nil, ErrInvalidPath
+	}
+
+	return fn, nil
+}
+
+// asTest turns an operand used on its own into a test expression: an existence test
+// for queries, or a call of a function returning a logical result
+func asTest(operand interface{}) (logicalExpr, error) {
+	switch x := operand.(type) {
+	case *queryExpr:
+		return &existsExpr{query: x}, nil
+	case *functionExpr:
+		if x.name == "match" || x.name == "search" {
+			return x, nil
+		}
+	}
+	return nil, ErrInvalidPath
+}
+
+// asComparable checks that an operand produces a single value: a literal, a singular
+// query or a function returning a value
+func asComparable(operand interface{}) (valueExpr, error) {
+	switch x := operand.(type) {
+	case *literalExpr:
+		return x, nil
+	case *queryExpr:
+		if !x.isSingular() {
+			return nil, ErrInvalidPath
+		}
+		return x, nil
+	case *functionExpr:
+		if x.name == "length" || x.name == "count" || x.name == "value" {
+			return x, nil
+		}
+	}
+	return nil, ErrInvalidPath
+}
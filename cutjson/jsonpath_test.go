@@ -0,0 +1,113 @@
+package cutjson
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestJSONPath(t *testing.T) {
+	jsonData := []byte(`{
+		"store": {
+			"book": [
+				{"category": "reference", "author": "Nigel Rees", "title": "Sayings of the Century", "price": 8.95},
+				{"category": "fiction", "author": "Evelyn Waugh", "title": "Sword of Honour", "price": 12.99},
+				{"category": "fiction", "author": "Herman Melville", "title": "Moby Dick", "isbn": "0-553-21311-3", "price": 8.99},
+				{"category": "fiction", "author": "J. R. R. Tolkien", "title": "The Lord of the Rings", "isbn": "0-395-19395-8", "price": 22.99}
+			],
+			"bicycle": {"color": "red", "price": 399}
+		},
+		"expensive": 10
+	}`)
+
+	cut := func(path string) string {
+		result, err := Cut(jsonData, path)
+		So(err, ShouldBeNil)
+		return string(result)
+	}
+
+	Convey("测试JSONPath路径", t, func() {
+		Convey("成员和索引选择器", func() {
+			So(cut("$.store.bicycle.color"), ShouldEqual, `{"store":{"bicycle":{"color":"red"}}}`)
+			So(cut("$['store']['book'][-1].author"), ShouldEqual, `{"store":{"book":[{"author":"J. R. R. Tolkien"}]}}`)
+			So(cut("$.store.book[0,2].price"), ShouldEqual, `{"store":{"book":[{"price":8.95},{"price":8.99}]}}`)
+		})
+
+		Convey("通配符和任意深度", func() {
+			So(cut("$..author"), ShouldEqual,
+				`{"store":{"book":[{"author":"Nigel Rees"},{"author":"Evelyn Waugh"},{"author":"Herman Melville"},{"author":"J. R. R. Tolkien"}]}}`)
+			So(cut("$.store.*.color"), ShouldEqual, `{"store":{"bicycle":{"color":"red"}}}`)
+			So(cut("$..book[*].isbn"), ShouldEqual, `{"store":{"book":[{"isbn":"0-553-21311-3"},{"isbn":"0-395-19395-8"}]}}`)
+		})
+
+		Convey("过滤表达式", func() {
+			So(cut("$.store.book[?@.price < 10].title"), ShouldEqual,
+				`{"store":{"book":[{"title":"Sayings of the Century"},{"title":"Moby Dick"}]}}`)
+			So(cut("$.store.book[?@.price > $.expensive].title"), ShouldEqual,
+				`{"store":{"book":[{"title":"Sword of Honour"},{"title":"The Lord of the Rings"}]}}`)
+			So(cut("$..book[?@.isbn].title"), ShouldEqual,
+				`{"store":{"book":[{"title":"Moby Dick"},{"title":"The Lord of the Rings"}]}}`)
+			So(cut("$..book[?!@.isbn && @.category == 'fiction'].title"), ShouldEqual,
+				`{"store":{"book":[{"title":"Sword of Honour"}]}}`)
+			So(cut(`$..book[?(@.price < 9 || @.price > 20) && @.category != "reference"].title`), ShouldEqual,
+				`{"store":{"book":[{"title":"Moby Dick"},{"title":"The Lord of the Rings"}]}}`)
+			So(cut("$.store[?@.color == 'red']"), ShouldEqual, `{"store":{"bicycle":{"color": "red", "price": 399}}}`)
+		})
+
+		Convey("函数扩展", func() {
+			So(cut("$.store.book[?length(@.title) > 20].price"), ShouldEqual,
+				`{"store":{"book":[{"price":8.95},{"price":22.99}]}}`)
+			So(cut("$.store.book[?match(@.author, 'J.*')].title"), ShouldEqual,
+				`{"store":{"book":[{"title":"The Lord of the Rings"}]}}`)
+			So(cut("$.store.book[?search(@.author, 'Mel')].title"), ShouldEqual,
+				`{"store":{"book":[{"title":"Moby Dick"}]}}`)
+			So(cut("$.store[?count(@.*) == 2].color"), ShouldEqual, `{"store":{"bicycle":{"color":"red"}}}`)
+			So(cut("$.store.book[?value(@..isbn) == '0-395-19395-8'].title"), ShouldEqual,
+				`{"store":{"book":[{"title":"The Lord of the Rings"}]}}`)
+		})
+
+		Convey("根节点", func() {
+			So(cut("$"), ShouldEqual, strings.TrimSpace(string(jsonData)))
+		})
+
+		Convey("在条件规则中使用JSONPath", func() {
+			rules := []Rule{
+				NewKeepArrayElementsIfChildValueMatchesRule("$.store.book", "@.category", "reference"),
+			}
+			result, err := CutWithRules(jsonData, rules)
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual,
+				`{"store":{"book":[{"author":"Nigel Rees","category":"reference","price":8.95,"title":"Sayings of the Century"}]}}`)
+
+			rule := NewKeepPathRule("store.bicycle")
+			rule.PathSyntax = PathJSONPath
+			_, err = CutWithRules(jsonData, []Rule{rule})
+			So(err, ShouldEqual, ErrInvalidPath)
+		})
+
+		Convey("无效的JSONPath", func() {
+			for _, path := range []string{
+				"$.", "$[", "$['a'", "$[01]", "$[?@.a ==]", "$[?@.* == 1]", "$[?length(@.a)]",
+				"$[?foo(@.a)]", "$.a b", "$[?match(@.a, '[')]", "$[?(@.a]",
+			} {
+				_, err := parseJSONPath(path)
+				So(err, ShouldEqual, ErrInvalidPath)
+			}
+		})
+	})
+}
+
+// normalizeJSON re-encodes JSON test data the way the cut result is encoded
+func normalizeJSON(data []byte) []byte {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		panic(err)
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
@@ -0,0 +1,88 @@
+package cutjson
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaskMode defines how a masked value is replaced
+type MaskMode int
+
+const (
+	// MaskFixed 使用固定文本替换，默认为"***"
+	MaskFixed MaskMode = iota
+	// MaskKeepLast 只保留最后N个字符，其余字符替换为*
+	MaskKeepLast
+	// MaskHash 替换为加盐后的SHA-256十六进制摘要
+	MaskHash
+	// MaskNull 替换为null
+	MaskNull
+)
+
+// defaultMaskText is the replacement used by MaskFixed when no text is configured
+const defaultMaskText = "***"
+
+// Mask describes how a MaskPath rule replaces the values it matches
+type Mask struct {
+	Mode     MaskMode // 替换方式
+	Text     string   // 固定替换文本（用于MaskFixed），为空时使用"***"
+	KeepLast int      // 保留的末尾字符数（用于MaskKeepLast）
+	Salt     string   // 计算摘要前拼接在值前面的盐（用于MaskHash）
+}
+
+// apply returns the replacement for value. Values other than strings are masked by their
+// JSON text, so a number or an object can be hashed or partially revealed as well
+func (m Mask) apply(value interface{}) (interface{}, error) {
+	switch m.Mode {
+	case MaskFixed:
+		if m.Text == "" {
+			return defaultMaskText, nil
+		}
+		return m.Text, nil
+
+	case MaskNull:
+		return nil, nil
+
+	case MaskKeepLast:
+		if m.KeepLast < 0 {
+			return nil, ErrInvalidRule
+		}
+		text, err := maskText(value)
+		if err != nil {
+			return nil, err
+		}
+		n := utf8.RuneCountInString(text)
+		if n <= m.KeepLast {
+			// Revealing every character would defeat the mask
+			return strings.Repeat("*", n), nil
+		}
+		runes := []rune(text)
+		return strings.Repeat("*", n-m.KeepLast) + string(runes[n-m.KeepLast:]), nil
+
+	case MaskHash:
+		text, err := maskText(value)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256([]byte(m.Salt + text))
+		return hex.EncodeToString(sum[:]), nil
+
+	default:
+		return nil, ErrInvalidRule
+	}
+}
+
+// maskText returns the text a mask works on: a string itself or the JSON encoding of any other value
+func maskText(value interface{}) (string, error) {
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
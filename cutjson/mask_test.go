@@ -0,0 +1,67 @@
+package cutjson
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMaskRules(t *testing.T) {
+	jsonData := []byte(`{
+		"user": {"name": "John Doe", "email": "john@example.com", "card": "4111111111111234", "pin": 1234},
+		"orders": [
+			{"id": 1, "status": "shipped", "card": "5500000000000004"},
+			{"id": 2, "status": "pending", "card": "340000000000009"}
+		]
+	}`)
+
+	cut := func(rules ...Rule) string {
+		result, err := CutWithRules(jsonData, rules)
+		So(err, ShouldBeNil)
+		return string(result)
+	}
+
+	Convey("测试替换规则", t, func() {
+		Convey("只有替换规则时从完整文档开始", func() {
+			So(cut(NewMaskPathRule("user.email", Mask{}), NewMaskPathRule("orders.*.card", Mask{Mode: MaskNull})), ShouldEqual,
+				`{"orders":[{"card":null,"id":1,"status":"shipped"},{"card":null,"id":2,"status":"pending"}],`+
+					`"user":{"card":"4111111111111234","email":"***","name":"John Doe","pin":1234}}`)
+		})
+
+		Convey("固定文本和保留末尾字符", func() {
+			So(cut(NewKeepPathRule("user.card"), NewMaskPathRule("user.card", Mask{Mode: MaskKeepLast, KeepLast: 4})), ShouldEqual,
+				`{"user":{"card":"************1234"}}`)
+			So(cut(NewKeepPathRule("user.pin"), NewMaskPathRule("user.pin", Mask{Mode: MaskKeepLast, KeepLast: 4})), ShouldEqual,
+				`{"user":{"pin":"****"}}`)
+			So(cut(NewKeepPathRule("user.email"), NewMaskPathRule("user.email", Mask{Text: "[redacted]"})), ShouldEqual,
+				`{"user":{"email":"[redacted]"}}`)
+		})
+
+		Convey("加盐的SHA-256摘要", func() {
+			sum := sha256.Sum256([]byte("pepper" + "john@example.com"))
+			So(cut(NewKeepPathRule("user.email"), NewMaskPathRule("user.email", Mask{Mode: MaskHash, Salt: "pepper"})), ShouldEqual,
+				`{"user":{"email":"`+hex.EncodeToString(sum[:])+`"}}`)
+		})
+
+		Convey("只替换结果中保留的值", func() {
+			rules := []Rule{
+				NewKeepArrayElementsIfChildValueMatchesRule("orders", "status", "pending"),
+				NewMaskPathRule("orders.*.card", Mask{Mode: MaskKeepLast, KeepLast: 4}),
+				NewMaskPathRule("user.email", Mask{}),
+			}
+			So(cut(rules...), ShouldEqual, `{"orders":[{"card":"***********0009","id":2,"status":"pending"}]}`)
+		})
+
+		Convey("替换在移除之后应用", func() {
+			So(cut(NewDropPathRule("user"), NewMaskPathRule("user.email", Mask{})), ShouldEqual,
+				`{"orders":[{"card":"5500000000000004","id":1,"status":"shipped"},{"card":"340000000000009","id":2,"status":"pending"}]}`)
+		})
+
+		Convey("无效的替换方式返回错误", func() {
+			_, err := CutWithRules(jsonData, []Rule{NewMaskPathRule("user.email", Mask{Mode: MaskMode(9)})})
+			So(err, ShouldEqual, ErrInvalidRule)
+		})
+	})
+}
@@ -0,0 +1,174 @@
+package cutjson
+
+// ConflictPolicy decides what happens when several rules keep overlapping parts of the document
+type ConflictPolicy int
+
+const (
+	// ConflictUnion 深度合并：对象按键合并，数组按原始索引合并，保留所有规则选中的内容
+	ConflictUnion ConflictPolicy = iota
+	// ConflictLastWins 后面的规则选中的子树替换前面规则在同一位置保留的内容
+	ConflictLastWins
+	// ConflictFirstWins 已经被前面规则保留的位置不再被后面的规则修改
+	ConflictFirstWins
+	// ConflictIntersection 只保留所有规则都选中的内容
+	ConflictIntersection
+)
+
+// place puts value below dst following steps and resolves a collision at the target position
+// according to policy; src is the source value at the same position as dst
+func place(dst interface{}, exists bool, src interface{}, steps []step, value interface{}, policy ConflictPolicy) interface{} {
+	if len(steps) == 0 {
+		if !exists {
+			return value
+		}
+		switch policy {
+		case ConflictLastWins:
+			return value
+		case ConflictFirstWins:
+			return dst
+		default:
+			return mergeValues(dst, value)
+		}
+	}
+
+	s := steps[0]
+	if s.isIndex {
+		srcArray, _ := src.([]interface{})
+		arr, ok := expand(dst).(*array)
+		if !ok {
+			arr = newArray(len(srcArray))
+		}
+		var child interface{}
+		if s.index < len(srcArray) {
+			child = srcArray[s.index]
+		}
+		current, found := arr.items[s.index]
+		arr.items[s.index] = place(current, found, child, steps[1:], value, policy)
+		return arr
+	}
+
+	srcObject, _ := src.(map[string]interface{})
+	obj, ok := expand(dst).(object)
+	if !ok {
+		obj = make(object)
+	}
+	current, found := obj[s.key]
+	obj[s.key] = place(current, found, srcObject[s.key], steps[1:], value, policy)
+	return obj
+}
+
+// mergeValues deep-merges two values kept at the same position of the document:
+// objects are merged key by key and arrays by source index
+func mergeValues(a, b interface{}) interface{} {
+	if isSourceContainer(a) && isSourceContainer(b) {
+		// Both sides keep the same source subtree as a whole
+		return b
+	}
+
+	switch x := expand(a).(type) {
+	case object:
+		y, ok := expand(b).(object)
+		if !ok {
+			return b
+		}
+		out := make(object, len(x)+len(y))
+		for k, v := range x {
+			out[k] = v
+		}
+		for k, v := range y {
+			if current, found := out[k]; found {
+				out[k] = mergeValues(current, v)
+			} else {
+				out[k] = v
+			}
+		}
+		return out
+
+	case *array:
+		y, ok := expand(b).(*array)
+		if !ok {
+			return b
+		}
+		out := newArray(x.length)
+		if y.length > out.length {
+			out.length = y.length
+		}
+		for i, v := range x.items {
+			out.items[i] = v
+		}
+		for i, v := range y.items {
+			if current, found := out.items[i]; found {
+				out.items[i] = mergeValues(current, v)
+			} else {
+				out.items[i] = v
+			}
+		}
+		return out
+
+	default:
+		return b
+	}
+}
+
+// intersectValues keeps only the parts present in both a and b; the boolean result
+// is false when nothing is left
+func intersectValues(a, b interface{}) (interface{}, bool) {
+	if isSourceContainer(a) && isSourceContainer(b) {
+		return b, true
+	}
+
+	switch x := expand(a).(type) {
+	case object:
+		y, ok := expand(b).(object)
+		if !ok {
+			return b, true
+		}
+		if len(x) == 0 && len(y) == 0 {
+			return y, true
+		}
+		out := make(object)
+		for k, v := range y {
+			current, found := x[k]
+			if !found {
+				continue
+			}
+			if kept, ok := intersectValues(current, v); ok {
+				out[k] = kept
+			}
+		}
+		return out, len(out) > 0
+
+	case *array:
+		y, ok := expand(b).(*array)
+		if !ok {
+			return b, true
+		}
+		if len(x.items) == 0 && len(y.items) == 0 {
+			return y, true
+		}
+		out := newArray(y.length)
+		for i, v := range y.items {
+			current, found := x.items[i]
+			if !found {
+				continue
+			}
+			if kept, ok := intersectValues(current, v); ok {
+				out.items[i] = kept
+			}
+		}
+		return out, len(out.items) > 0
+
+	default:
+		return b, true
+	}
+}
+
+// isSourceContainer reports whether v is an object or array taken unchanged from the source document
+func isSourceContainer(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,110 @@
+package cutjson
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMergeRules(t *testing.T) {
+	jsonData := []byte(`{
+		"user": {"name": "John Doe", "age": 30, "verified": true},
+		"products": [
+			{"id": 101, "category": "electronics", "inStock": true},
+			{"id": 102, "category": "electronics", "inStock": false},
+			{"id": 103, "category": "accessories", "inStock": true},
+			{"id": 104, "category": "accessories", "inStock": false}
+		]
+	}`)
+
+	arrayRules := []Rule{
+		NewKeepArrayElementsIfChildValueMatchesRule("products", "category", "electronics"),
+		NewKeepArrayElementsIfChildValueMatchesRule("products", "inStock", true),
+	}
+
+	cut := func(rules []Rule, policy ConflictPolicy) string {
+		result, err := CutWithOptions(jsonData, rules, Options{Conflict: policy})
+		So(err, ShouldBeNil)
+		return string(result)
+	}
+
+	Convey("测试多个规则结果的合并", t, func() {
+		Convey("默认合并两个数组规则保留的元素，保持原始顺序", func() {
+			So(cut(arrayRules, ConflictUnion), ShouldEqual,
+				`{"products":[{"category":"electronics","id":101,"inStock":true},{"category":"electronics","id":102,"inStock":false},{"category":"accessories","id":103,"inStock":true}]}`)
+		})
+
+		Convey("保留父对象不会覆盖之前保留的子字段", func() {
+			rules := []Rule{
+				NewKeepPathRule("user.name"),
+				NewKeepPathRule("user"),
+				NewKeepPathRule("user.age"),
+			}
+			So(cut(rules, ConflictUnion), ShouldEqual, `{"user":{"age":30,"name":"John Doe","verified":true}}`)
+		})
+
+		Convey("兄弟字段按键合并", func() {
+			rules := []Rule{
+				NewKeepPathRule("products.0.id"),
+				NewKeepPathRule("products.0.category"),
+				NewKeepPathRule("products.2.id"),
+			}
+			So(cut(rules, ConflictUnion), ShouldEqual, `{"products":[{"category":"electronics","id":101},{"id":103}]}`)
+		})
+
+		Convey("后面的规则优先", func() {
+			So(cut(arrayRules, ConflictLastWins), ShouldEqual,
+				`{"products":[{"category":"electronics","id":101,"inStock":true},{"category":"accessories","id":103,"inStock":true}]}`)
+
+			rules := []Rule{NewKeepPathRule("user"), NewKeepPathRule("user.name")}
+			So(cut(rules, ConflictLastWins), ShouldEqual, `{"user":{"age":30,"name":"John Doe","verified":true}}`)
+		})
+
+		Convey("前面的规则优先", func() {
+			So(cut(arrayRules, ConflictFirstWins), ShouldEqual,
+				`{"products":[{"category":"electronics","id":101,"inStock":true},{"category":"electronics","id":102,"inStock":false}]}`)
+
+			rules := []Rule{NewKeepPathRule("user.name"), NewKeepPathRule("user")}
+			So(cut(rules, ConflictFirstWins), ShouldEqual, `{"user":{"name":"John Doe"}}`)
+		})
+
+		Convey("只保留所有规则都选中的内容", func() {
+			So(cut(arrayRules, ConflictIntersection), ShouldEqual,
+				`{"products":[{"category":"electronics","id":101,"inStock":true}]}`)
+
+			rules := []Rule{NewKeepPathRule("user"), NewKeepPathRule("user.name")}
+			So(cut(rules, ConflictIntersection), ShouldEqual, `{"user":{"name":"John Doe"}}`)
+
+			rules = []Rule{NewKeepPathRule("user.name"), NewKeepPathRule("user.age")}
+			So(cut(rules, ConflictIntersection), ShouldEqual, `{}`)
+		})
+	})
+}
+
+func TestLoadConfigOptions(t *testing.T) {
+	Convey("测试从配置文件加载裁剪选项", t, func() {
+		configFile, err := os.CreateTemp(t.TempDir(), "config-*.json")
+		So(err, ShouldBeNil)
+		_, err = configFile.WriteString(`{
+			"options": {"array_mode": "preserve", "placeholder": 0, "conflict": "intersection", "invalid_lines": "skip"},
+			"rules": [{"type": "keep_path", "where": "user.name"}]
+		}`)
+		So(err, ShouldBeNil)
+		So(configFile.Close(), ShouldBeNil)
+
+		rules, opts, err := LoadConfig(configFile.Name())
+		So(err, ShouldBeNil)
+		So(len(rules), ShouldEqual, 1)
+		So(opts.ArrayMode, ShouldEqual, PreserveArrayPositions)
+		So(opts.Placeholder, ShouldEqual, json.Number("0"))
+		So(opts.Conflict, ShouldEqual, ConflictIntersection)
+		So(opts.InvalidLines, ShouldEqual, InvalidLineSkip)
+
+		_, err = ParseConflictPolicy("random")
+		So(err, ShouldNotBeNil)
+		_, err = ParseInvalidLinePolicy("random")
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,64 @@
+package cutjson
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// InvalidLinePolicy decides what CutStream does with a line that is not valid JSON
+type InvalidLinePolicy int
+
+const (
+	// InvalidLineAbort 遇到无效的行时停止处理并返回错误
+	InvalidLineAbort InvalidLinePolicy = iota
+	// InvalidLineSkip 跳过无效的行，不输出任何内容
+	InvalidLineSkip
+	// InvalidLineNull 无效的行输出null
+	InvalidLineNull
+)
+
+// CutStream cuts every record of r, a stream of newline-delimited JSON (NDJSON / JSON Lines), on its
+// own with cutter and writes one result per line to w. Blank lines are skipped, and a line that is not
+// valid JSON is handled according to the cutter's Options.InvalidLines; any other error stops the
+// stream and is returned with the number of the line it occurred on
+func CutStream(r io.Reader, w io.Writer, cutter *Cutter) error {
+	in := bufio.NewReader(r)
+	out := bufio.NewWriter(w)
+
+	for line := 1; ; line++ {
+		record, readErr := in.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			out.Flush()
+			return readErr
+		}
+
+		if record = bytes.Trim(record, " \t\r\n"); len(record) > 0 {
+			result, err := cutter.Cut(record)
+			if err == ErrInvalidJSON {
+				switch cutter.opts.InvalidLines {
+				case InvalidLineSkip:
+					result, err = nil, nil
+				case InvalidLineNull:
+					result, err = []byte("null"), nil
+				}
+			}
+			if err != nil {
+				out.Flush()
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+
+			if result != nil {
+				out.Write(result)
+				if err := out.WriteByte('\n'); err != nil {
+					return err
+				}
+			}
+		}
+
+		if readErr == io.EOF {
+			return out.Flush()
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package cutjson
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCutStream(t *testing.T) {
+	input := "{\"id\": 1, \"user\": {\"name\": \"a\", \"email\": \"a@example.com\"}}\n" +
+		"\n" +
+		"{\"user\": {\"email\": \"b@example.com\", \"name\": \"b\"}, \"id\": 2}\r\n" +
+		"{\"id\": 3, \"user\": \n" +
+		"   \n" +
+		"{\"id\": 4}"
+
+	stream := func(rules []Rule, opts Options) (string, error) {
+		cutter, err := CompileWithOptions(rules, opts)
+		So(err, ShouldBeNil)
+
+		var out bytes.Buffer
+		err = CutStream(strings.NewReader(input), &out, cutter)
+		return out.String(), err
+	}
+
+	rules := []Rule{NewKeepPathRule("id"), NewMaskPathRule("user.email", Mask{Mode: MaskFixed})}
+
+	Convey("测试逐行裁剪NDJSON", t, func() {
+		Convey("每条记录单独裁剪并输出一行", func() {
+			result, err := stream(rules, Options{InvalidLines: InvalidLineSkip})
+			So(err, ShouldBeNil)
+			So(result, ShouldEqual, "{\"id\":1}\n{\"id\":2}\n{\"id\":4}\n")
+
+			result, err = stream([]Rule{NewKeepPathRule("user"), NewMaskPathRule("user.email", Mask{Mode: MaskFixed})},
+				Options{InvalidLines: InvalidLineSkip, PreserveKeyOrder: true})
+			So(err, ShouldBeNil)
+			So(result, ShouldEqual, "{\"user\":{\"name\":\"a\",\"email\":\"***\"}}\n{\"user\":{\"email\":\"***\",\"name\":\"b\"}}\n{}\n")
+		})
+
+		Convey("无效的行输出null", func() {
+			result, err := stream(rules, Options{InvalidLines: InvalidLineNull})
+			So(err, ShouldBeNil)
+			So(result, ShouldEqual, "{\"id\":1}\n{\"id\":2}\nnull\n{\"id\":4}\n")
+		})
+
+		Convey("遇到无效的行时停止", func() {
+			result, err := stream(rules, Options{})
+			So(errors.Is(err, ErrInvalidJSON), ShouldBeTrue)
+			So(err.Error(), ShouldEqual, "line 4: invalid JSON input")
+			So(result, ShouldEqual, "{\"id\":1}\n{\"id\":2}\n")
+		})
+
+		Convey("其他错误总是停止处理", func() {
+			result, err := stream([]Rule{NewKeepArrayElementsIfChildValueMatchesRule("user", "name", "a")}, Options{InvalidLines: InvalidLineSkip})
+			So(errors.Is(err, errNotArray), ShouldBeTrue)
+			So(err.Error(), ShouldStartWith, "line 1: ")
+			So(result, ShouldEqual, "")
+		})
+
+		Convey("空输入不输出任何内容", func() {
+			cutter, err := Compile(rules)
+			So(err, ShouldBeNil)
+
+			var out bytes.Buffer
+			So(CutStream(strings.NewReader(""), &out, cutter), ShouldBeNil)
+			So(CutStream(strings.NewReader("\n \n"), &out, cutter), ShouldBeNil)
+			So(out.String(), ShouldEqual, "")
+		})
+	})
+}
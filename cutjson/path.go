@@ -0,0 +1,305 @@
+package cutjson
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// step is one concrete move through a JSON document: an object key or a resolved array index
+type step struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// match is a value found in the document together with the concrete steps leading to it
+type match struct {
+	steps []step
+	value interface{}
+}
+
+// appendStep returns a new slice with s appended, leaving steps untouched
+func appendStep(steps []step, s step) []step {
+	out := make([]step, len(steps), len(steps)+1)
+	copy(out, steps)
+	return append(out, s)
+}
+
+// resolve returns every value matched by the path segments, with negative array
+// indices resolved against the source length
+func resolve(data interface{}, segments []segment) ([]match, error) {
+	return resolveFrom(data, match{value: data}, segments)
+}
+
+// resolveFrom is like resolve but starts at the value of from, a node inside the document root;
+// the returned steps lead from root, and filter queries starting with $ still refer to root
+func resolveFrom(root interface{}, from match, segments []segment) ([]match, error) {
+	var matches []match
+
+	w := &pathWalker{root: root, visit: func(steps []step, value interface{}) {
+		matches = append(matches, match{steps: steps, value: value})
+	}}
+	if err := w.walk(from.value, segments, from.steps); err != nil {
+		return nil, err
+	}
+
+	if len(matches) == 0 {
+		return nil, ErrPathNotFound
+	}
+
+	return matches, nil
+}
+
+// pathWalker traverses a JSON document following path segments and calls visit for each matched value;
+// root is the document that absolute queries inside filter expressions refer to
+type pathWalker struct {
+	root  interface{}
+	visit func([]step, interface{})
+}
+
+// walk follows the segments from data, where steps lead from the root to data
+func (w *pathWalker) walk(data interface{}, segments []segment, steps []step) error {
+	if len(segments) == 0 {
+		w.visit(steps, data)
+		return nil
+	}
+
+	seg := segments[0]
+	remaining := segments[1:]
+
+	if seg.kind == descendantSegment {
+		return w.walkDescendants(data, remaining, steps)
+	}
+
+	return w.selectChildren(data, seg, steps, func(childSteps []step, child interface{}) error {
+		return w.walk(child, remaining, childSteps)
+	})
+}
+
+// walkDescendants matches the remaining segments against data and every value nested below it
+func (w *pathWalker) walkDescendants(data interface{}, segments []segment, steps []step) error {
+	// A name segment meeting an array at some depth simply does not match there
+	if err := w.walk(data, segments, steps); err != nil && err != ErrInvalidPath {
+		return err
+	}
+
+	return walkChildren(data, steps, func(childSteps []step, child interface{}) error {
+		return w.walkDescendants(child, segments, childSteps)
+	})
+}
+
+// selectChildren calls fn for every child of data selected by a single segment
+func (w *pathWalker) selectChildren(data interface{}, seg segment, steps []step, fn func([]step, interface{}) error) error {
+	data = rawChildren(data)
+
+	switch seg.kind {
+	case wildcardSegment:
+		return walkChildren(data, steps, fn)
+
+	case unionSegment:
+		for _, selector := range seg.selectors {
+			if err := w.selectChildren(data, selector, steps, fn); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case sliceSegment:
+		v, ok := data.([]interface{})
+		if !ok {
+			return nil
+		}
+		for _, i := range seg.slice.indices(len(v)) {
+			if err := fn(appendStep(steps, step{index: i, isIndex: true}), v[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case filterSegment:
+		return walkChildren(data, steps, func(childSteps []step, child interface{}) error {
+			if !seg.filter.test(&filterContext{root: w.root, current: plainValue(child)}) {
+				return nil
+			}
+			return fn(childSteps, child)
+		})
+
+	case fieldSegment, maskFieldSegment:
+		if v, ok := data.([]interface{}); ok {
+			for i, element := range v {
+				if err := w.selectChildren(element, seg, appendStep(steps, step{index: i, isIndex: true}), fn); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if seg.kind == maskFieldSegment {
+			return walkMaskField(data, seg.name, steps, fn)
+		}
+	}
+
+	switch v := data.(type) {
+	case map[string]interface{}:
+		if seg.kind != nameSegment && seg.kind != pointerSegment && seg.kind != fieldSegment {
+			return nil
+		}
+		val, ok := v[seg.name]
+		if !ok {
+			return nil
+		}
+		return fn(appendStep(steps, step{key: seg.name}), val)
+
+	case []interface{}:
+		index, ok, err := arrayIndex(seg)
+		if err != nil || !ok {
+			return err
+		}
+
+		index, ok = resolveIndex(index, len(v))
+		if !ok {
+			return nil
+		}
+		return fn(appendStep(steps, step{index: index, isIndex: true}), v[index])
+
+	default:
+		return nil
+	}
+}
+
+// walkMaskField calls fn for every member of an object whose name matches a FieldMask field name
+func walkMaskField(data interface{}, name string, steps []step, fn func([]step, interface{}) error) error {
+	v, ok := data.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	_, exact := v[name]
+	return walkChildren(v, steps, func(childSteps []step, child interface{}) error {
+		if !selectsMaskField(name, childSteps[len(childSteps)-1].key, exact) {
+			return nil
+		}
+		return fn(childSteps, child)
+	})
+}
+
+// selectsMaskField reports whether a FieldMask field name selects the object member named key;
+// exact reports whether the object has a member spelled exactly like name, which then wins over
+// the case- and underscore-insensitive comparison
+func selectsMaskField(name, key string, exact bool) bool {
+	if exact {
+		return key == name
+	}
+	return foldFieldName(key) == foldFieldName(name)
+}
+
+// arrayIndex returns the index a name, index or pointer segment selects in an array, counting from
+// the end when it is negative; ok is false when the segment never selects an array element
+func arrayIndex(seg segment) (index int, ok bool, err error) {
+	switch {
+	case seg.kind == indexSegment:
+		return seg.index, true, nil
+	case seg.kind == pointerSegment:
+		index, ok = pointerIndex(seg.name)
+		return index, ok, nil
+	case seg.kind == fieldSegment || seg.quoted:
+		return 0, false, nil
+	}
+
+	// A bare name must be a (possibly negative) index when applied to an array
+	if !isNumeric(seg.name) {
+		return 0, false, ErrInvalidPath
+	}
+	index, err = strconv.Atoi(seg.name)
+	if err != nil {
+		return 0, false, ErrInvalidPath
+	}
+	return index, true, nil
+}
+
+// foldFieldName maps snake_case and camelCase spellings of a field name to the same key
+func foldFieldName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
+
+// walkChildren calls fn for every member of an object, in key order, or every element of an array
+func walkChildren(data interface{}, steps []step, fn func([]step, interface{}) error) error {
+	switch v := rawChildren(data).(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			if err := fn(appendStep(steps, step{key: k}), v[k]); err != nil {
+				return err
+			}
+		}
+
+	case []interface{}:
+		for i, element := range v {
+			if err := fn(appendStep(steps, step{index: i, isIndex: true}), element); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// valueAt returns the value reached by following concrete steps from data
+func valueAt(data interface{}, steps []step) interface{} {
+	current := data
+	for _, s := range steps {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			current = v[s.key]
+		case []interface{}:
+			current = v[s.index]
+		default:
+			return nil
+		}
+	}
+	return current
+}
+
+// resolveIndex counts a negative index from the end of the array and checks that it is in range
+func resolveIndex(index int, length int) (int, bool) {
+	// Convert negative index to positive
+	if index < 0 {
+		index = length + index
+	}
+
+	if index < 0 || index >= length {
+		return 0, false
+	}
+
+	return index, true
+}
+
+// isNumeric checks if a string represents a numeric value
+func isNumeric(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+
+	// Check for negative sign
+	start := 0
+	if s[0] == '-' {
+		if len(s) == 1 {
+			return false
+		}
+		start = 1
+	}
+
+	// Check if all remaining characters are digits
+	for i := start; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,501 @@
+package cutjson
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// segmentKind identifies what a path segment matches
+type segmentKind int
+
+const (
+	// nameSegment selects an object member; a bare numeric name also selects an array element
+	nameSegment segmentKind = iota
+	// indexSegment selects an array element, written as [n] or [-n]
+	indexSegment
+	// wildcardSegment selects every object member or array element, written as * or [*]
+	wildcardSegment
+	// descendantSegment matches any number of levels including none, written as ** or ..
+	descendantSegment
+	// pointerSegment is a JSON Pointer reference token: an object member, or an array
+	// element when the token is an unsigned decimal index
+	pointerSegment
+	// unionSegment applies several selectors in turn, written as [a,b] in JSONPath
+	unionSegment
+	// filterSegment selects the children for which a JSONPath filter expression holds
+	filterSegment
+	// sliceSegment selects a range of array elements, written as [start:end:step]
+	sliceSegment
+	// fieldSegment selects an object member; applied to an array it selects the member of every
+	// element instead, as in a partial-response field selector
+	fieldSegment
+	// maskFieldSegment is a protobuf FieldMask field name: it selects the object members whose names
+	// equal it ignoring case and underscores, so display_name matches displayName, and maps over arrays
+	maskFieldSegment
+)
+
+// segment is one parsed element of a path expression
+type segment struct {
+	kind   segmentKind
+	name   string
+	index  int
+	quoted bool // quoted or escaped names are taken literally and never select array elements
+
+	selectors []segment   // selectors of a unionSegment
+	filter    logicalExpr // expression of a filterSegment
+	slice     sliceBounds // bounds of a sliceSegment
+}
+
+// sliceBounds holds the optional start and end and the step of a slice segment
+type sliceBounds struct {
+	start, end       int
+	hasStart, hasEnd bool
+	step             int
+}
+
+// indices returns the indices selected by the slice in an array of the given length, in
+// selection order; negative bounds count from the end and a negative step walks backwards
+// (RFC 9535 section 2.3.4.2.2). A cut keeps the selected elements in their source order, so a
+// negative step only decides which elements are selected, never their order in the result
+func (b sliceBounds) indices(length int) []int {
+	if b.step == 0 {
+		return nil
+	}
+
+	normalize := func(i int) int {
+		if i < 0 {
+			return length + i
+		}
+		return i
+	}
+	clamp := func(i, lower, upper int) int {
+		if i < lower {
+			return lower
+		}
+		if i > upper {
+			return upper
+		}
+		return i
+	}
+
+	var indices []int
+	if b.step > 0 {
+		lower, upper := 0, length
+		if b.hasStart {
+			lower = clamp(normalize(b.start), 0, length)
+		}
+		if b.hasEnd {
+			upper = clamp(normalize(b.end), 0, length)
+		}
+		for i := lower; i < upper; i += b.step {
+			indices = append(indices, i)
+		}
+		return indices
+	}
+
+	upper, lower := length-1, -1
+	if b.hasStart {
+		upper = clamp(normalize(b.start), -1, length-1)
+	}
+	if b.hasEnd {
+		lower = clamp(normalize(b.end), -1, length-1)
+	}
+	for i := upper; i > lower; i += b.step {
+		indices = append(indices, i)
+	}
+	return indices
+}
+
+// parseSlice parses start:end or start:end:step, where every part is optional and parseInt parses the parts
+func parseSlice(text string, parseInt func(string) (int, error)) (sliceBounds, error) {
+	parts := strings.Split(text, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return sliceBounds{}, ErrInvalidPath
+	}
+
+	bounds := sliceBounds{step: 1}
+	var err error
+
+	if part := strings.TrimSpace(parts[0]); part != "" {
+		bounds.hasStart = true
+		if bounds.start, err = parseInt(part); err != nil {
+			return sliceBounds{}, err
+		}
+	}
+	if part := strings.TrimSpace(parts[1]); part != "" {
+		bounds.hasEnd = true
+		if bounds.end, err = parseInt(part); err != nil {
+			return sliceBounds{}, err
+		}
+	}
+	if len(parts) == 3 {
+		if part := strings.TrimSpace(parts[2]); part != "" {
+			if bounds.step, err = parseInt(part); err != nil {
+				return sliceBounds{}, err
+			}
+		}
+	}
+
+	return bounds, nil
+}
+
+// parseIndex parses an optionally negative decimal array index
+func parseIndex(text string) (int, error) {
+	if !isNumeric(text) {
+		return 0, ErrInvalidPath
+	}
+	index, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, ErrInvalidPath
+	}
+	return index, nil
+}
+
+// PathSyntax selects the language a rule path is written in
+type PathSyntax int
+
+const (
+	// PathAuto 自动识别：以"/"开头的路径按JSON Pointer解析，以"$"开头的路径按JSONPath解析，
+	// 其他路径按点分隔语法解析
+	PathAuto PathSyntax = iota
+	// PathDotted 点分隔路径，如 user.address.city
+	PathDotted
+	// PathPointer RFC 6901 JSON Pointer，如 /user/address/city
+	PathPointer
+	// PathJSONPath RFC 9535 JSONPath，如 $.store.book[?@.price < 10].title
+	PathJSONPath
+	// PathFields 部分响应字段选择器中的单个字段路径，如 orders/items/productId，
+	// 字段作用于数组时对每个元素生效
+	PathFields
+	// PathFieldMask protobuf FieldMask路径，如 user.display_name，
+	// 字段名不区分snake_case和camelCase，字段作用于数组时对每个元素生效
+	PathFieldMask
+)
+
+// parseRulePath parses a rule path written in the given syntax
+func parseRulePath(path string, syntax PathSyntax) ([]segment, error) {
+	switch syntax {
+	case PathAuto:
+		if strings.HasPrefix(path, "/") {
+			return parsePointer(path)
+		}
+		if isJSONPath(path) {
+			return parseJSONPath(path)
+		}
+		return parsePath(path)
+	case PathDotted:
+		return parsePath(path)
+	case PathPointer:
+		return parsePointer(path)
+	case PathJSONPath:
+		return parseJSONPath(path)
+	case PathFields:
+		return parseFieldPath(path)
+	case PathFieldMask:
+		return parseFieldMaskPath(path)
+	default:
+		return nil, ErrInvalidPath
+	}
+}
+
+// parsePath parses a path expression into segments. Besides plain dotted names it understands
+// quoted segments ("en.US" or 'en.US'), backslash escapes (a.b\.c), bracket notation
+// (a["en.US"], a[0], a[-1], a[*]), slices (a[0:2], a[-3:], a[::2]) and the wildcard and recursive-descent segments
+func parsePath(path string) ([]segment, error) {
+	p := &pathParser{input: path}
+	return p.parse()
+}
+
+// parsePointer parses an RFC 6901 JSON Pointer such as /user/address/city; the URI fragment
+// form (#/user/address/city) is accepted as well. The empty pointer refers to the whole document
+func parsePointer(pointer string) ([]segment, error) {
+	if strings.HasPrefix(pointer, "#") {
+		unescaped, err := url.PathUnescape(pointer[1:])
+		if err != nil {
+			return nil, ErrInvalidPath
+		}
+		pointer = unescaped
+	}
+
+	if pointer == "" {
+		return []segment{}, nil
+	}
+	if pointer[0] != '/' {
+		return nil, ErrInvalidPath
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	segments := make([]segment, 0, len(tokens))
+	for _, token := range tokens {
+		name, err := unescapePointerToken(token)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, segment{kind: pointerSegment, name: name})
+	}
+
+	return segments, nil
+}
+
+// unescapePointerToken decodes ~1 to '/' and ~0 to '~'; any other use of '~' is invalid
+func unescapePointerToken(token string) (string, error) {
+	if !strings.Contains(token, "~") {
+		return token, nil
+	}
+
+	var s strings.Builder
+	for i := 0; i < len(token); i++ {
+		if token[i] != '~' {
+			s.WriteByte(token[i])
+			continue
+		}
+		if i+1 == len(token) {
+			return "", ErrInvalidPath
+		}
+		i++
+		switch token[i] {
+		case '0':
+			s.WriteByte('~')
+		case '1':
+			s.WriteByte('/')
+		default:
+			return "", ErrInvalidPath
+		}
+	}
+
+	return s.String(), nil
+}
+
+// pointerIndex parses a JSON Pointer array index: "0" or a decimal number without leading zeros
+func pointerIndex(token string) (int, bool) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, false
+	}
+	for i := 0; i < len(token); i++ {
+		if token[i] < '0' || token[i] > '9' {
+			return 0, false
+		}
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, false
+	}
+	return index, true
+}
+
+// pathParser holds the state of parsePath
+type pathParser struct {
+	input string
+	pos   int
+}
+
+func (p *pathParser) parse() ([]segment, error) {
+	var segments []segment
+
+	// A path starting with a bracket or ".." has no leading name
+	switch {
+	case strings.HasPrefix(p.input, ".."):
+	case strings.HasPrefix(p.input, "["):
+	default:
+		seg, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, seg)
+	}
+
+	for p.pos < len(p.input) {
+		switch {
+		case p.input[p.pos] == '[':
+			seg, err := p.parseBracket()
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, seg)
+
+		case strings.HasPrefix(p.input[p.pos:], ".."):
+			p.pos += 2
+			segments = append(segments, segment{kind: descendantSegment})
+			if p.pos == len(p.input) || p.input[p.pos] == '[' {
+				continue
+			}
+			seg, err := p.parseName()
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, seg)
+
+		case p.input[p.pos] == '.':
+			p.pos++
+			seg, err := p.parseName()
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, seg)
+
+		default:
+			return nil, ErrInvalidPath
+		}
+	}
+
+	return segments, nil
+}
+
+// parseName parses a dotted segment, which is either quoted or runs up to the next unescaped '.' or '['
+func (p *pathParser) parseName() (segment, error) {
+	if p.pos < len(p.input) && (p.input[p.pos] == '"' || p.input[p.pos] == '\'') {
+		name, err := p.parseQuoted()
+		if err != nil {
+			return segment{}, err
+		}
+		return segment{kind: nameSegment, name: name, quoted: true}, nil
+	}
+
+	var name strings.Builder
+	escaped := false
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		if c == '.' || c == '[' {
+			break
+		}
+		if c == '\\' {
+			if p.pos+1 == len(p.input) {
+				return segment{}, ErrInvalidPath
+			}
+			escaped = true
+			p.pos++
+			c = p.input[p.pos]
+		}
+		name.WriteByte(c)
+		p.pos++
+	}
+
+	if !escaped {
+		switch name.String() {
+		case "*":
+			return segment{kind: wildcardSegment}, nil
+		case "**":
+			return segment{kind: descendantSegment}, nil
+		}
+	}
+
+	return segment{kind: nameSegment, name: name.String(), quoted: escaped}, nil
+}
+
+// parseBracket parses a bracketed segment: a quoted name, an index, a slice or a wildcard
+func (p *pathParser) parseBracket() (segment, error) {
+	end := p.findClosingBracket()
+	if end < 0 {
+		return segment{}, ErrInvalidPath
+	}
+
+	p.pos++ // skip '['
+	p.skipSpaces()
+
+	var seg segment
+	switch {
+	case p.pos < end && (p.input[p.pos] == '"' || p.input[p.pos] == '\''):
+		name, err := p.parseQuoted()
+		if err != nil {
+			return segment{}, err
+		}
+		seg = segment{kind: nameSegment, name: name, quoted: true}
+
+	default:
+		content := strings.TrimSpace(p.input[p.pos:end])
+		p.pos = end
+		if content == "*" {
+			seg = segment{kind: wildcardSegment}
+			break
+		}
+		if strings.Contains(content, ":") {
+			bounds, err := parseSlice(content, parseIndex)
+			if err != nil {
+				return segment{}, err
+			}
+			seg = segment{kind: sliceSegment, slice: bounds}
+			break
+		}
+		index, err := parseIndex(content)
+		if err != nil {
+			return segment{}, err
+		}
+		seg = segment{kind: indexSegment, index: index}
+	}
+
+	p.skipSpaces()
+	if p.pos != end {
+		return segment{}, ErrInvalidPath
+	}
+	p.pos++ // skip ']'
+
+	return seg, nil
+}
+
+// findClosingBracket returns the position of the ']' closing the bracket at p.pos, skipping quoted text
+func (p *pathParser) findClosingBracket() int {
+	var quote byte
+	for i := p.pos + 1; i < len(p.input); i++ {
+		c := p.input[i]
+		switch {
+		case quote != 0 && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+// parseQuoted parses a string enclosed in single or double quotes; a backslash escapes the next character
+func (p *pathParser) parseQuoted() (string, error) {
+	quote := p.input[p.pos]
+	p.pos++
+
+	var s strings.Builder
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		switch c {
+		case quote:
+			p.pos++
+			return s.String(), nil
+		case '\\':
+			if p.pos+1 == len(p.input) {
+				return "", ErrInvalidPath
+			}
+			p.pos++
+			s.WriteByte(unescapeChar(p.input[p.pos]))
+		default:
+			s.WriteByte(c)
+		}
+		p.pos++
+	}
+
+	return "", ErrInvalidPath
+}
+
+func (p *pathParser) skipSpaces() {
+	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// unescapeChar maps the character following a backslash inside quotes to the character it stands for
+func unescapeChar(c byte) byte {
+	switch c {
+	case 'n':
+		return '\n'
+	case 't':
+		return '\t'
+	case 'r':
+		return '\r'
+	default:
+		return c
+	}
+}
@@ -0,0 +1,211 @@
+package cutjson
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParsePath(t *testing.T) {
+	Convey("测试路径解析", t, func() {
+		Convey("点分隔的路径", func() {
+			segments, err := parsePath("user.address.city")
+			So(err, ShouldBeNil)
+			So(segments, ShouldResemble, []segment{
+				{kind: nameSegment, name: "user"},
+				{kind: nameSegment, name: "address"},
+				{kind: nameSegment, name: "city"},
+			})
+		})
+
+		Convey("方括号和引号", func() {
+			segments, err := parsePath(`messages["en.US"]['a]b'][0][-1][*]`)
+			So(err, ShouldBeNil)
+			So(segments, ShouldResemble, []segment{
+				{kind: nameSegment, name: "messages"},
+				{kind: nameSegment, name: "en.US", quoted: true},
+				{kind: nameSegment, name: "a]b", quoted: true},
+				{kind: indexSegment, index: 0},
+				{kind: indexSegment, index: -1},
+				{kind: wildcardSegment},
+			})
+
+			segments, err = parsePath(`a."b.c".d`)
+			So(err, ShouldBeNil)
+			So(segments, ShouldResemble, []segment{
+				{kind: nameSegment, name: "a"},
+				{kind: nameSegment, name: "b.c", quoted: true},
+				{kind: nameSegment, name: "d"},
+			})
+		})
+
+		Convey("反斜杠转义", func() {
+			segments, err := parsePath(`a.b\.c.\*`)
+			So(err, ShouldBeNil)
+			So(segments, ShouldResemble, []segment{
+				{kind: nameSegment, name: "a"},
+				{kind: nameSegment, name: "b.c", quoted: true},
+				{kind: nameSegment, name: "*", quoted: true},
+			})
+		})
+
+		Convey("空键", func() {
+			segments, err := parsePath(`a[""].b`)
+			So(err, ShouldBeNil)
+			So(segments, ShouldResemble, []segment{
+				{kind: nameSegment, name: "a"},
+				{kind: nameSegment, name: "", quoted: true},
+				{kind: nameSegment, name: "b"},
+			})
+		})
+
+		Convey("通配符和任意深度", func() {
+			segments, err := parsePath("..id")
+			So(err, ShouldBeNil)
+			So(segments, ShouldResemble, []segment{
+				{kind: descendantSegment},
+				{kind: nameSegment, name: "id"},
+			})
+
+			segments, err = parsePath("a.**.*")
+			So(err, ShouldBeNil)
+			So(segments, ShouldResemble, []segment{
+				{kind: nameSegment, name: "a"},
+				{kind: descendantSegment},
+				{kind: wildcardSegment},
+			})
+		})
+
+		Convey("无效的路径", func() {
+			for _, path := range []string{`a["b`, `a[b]`, `a[0]b`, `a[0`, `a\`, `'a`} {
+				_, err := parsePath(path)
+				So(err, ShouldEqual, ErrInvalidPath)
+			}
+		})
+	})
+}
+
+func TestCutDottedKeys(t *testing.T) {
+	jsonData := []byte(`{
+		"messages": {"en.US": {"title": "Hello"}, "zh.CN": {"title": "你好"}, "": {"title": "?"}},
+		"list": [{"a.b": 1}, {"a.b": 2}]
+	}`)
+
+	Convey("测试裁剪包含点的键", t, func() {
+		result, err := Cut(jsonData, `messages["en.US"].title`)
+		So(err, ShouldBeNil)
+		So(string(result), ShouldEqual, `{"messages":{"en.US":{"title":"Hello"}}}`)
+
+		result, err = Cut(jsonData, `messages.zh\.CN`)
+		So(err, ShouldBeNil)
+		So(string(result), ShouldEqual, `{"messages":{"zh.CN":{"title": "你好"}}}`)
+
+		result, err = Cut(jsonData, `messages[""].title`)
+		So(err, ShouldBeNil)
+		So(string(result), ShouldEqual, `{"messages":{"":{"title":"?"}}}`)
+
+		result, err = Cut(jsonData, `list[-1]["a.b"]`)
+		So(err, ShouldBeNil)
+		So(string(result), ShouldEqual, `{"list":[{"a.b":2}]}`)
+
+		rules := []Rule{NewKeepArrayElementsIfChildValueMatchesRule("list", `"a.b"`, float64(1))}
+		result, err = CutWithRules(jsonData, rules)
+		So(err, ShouldBeNil)
+		So(string(result), ShouldEqual, `{"list":[{"a.b":1}]}`)
+
+		multi, errs, err := CutMultiple(jsonData, []string{`messages["en.US"]`})
+		So(err, ShouldBeNil)
+		So(errs, ShouldBeEmpty)
+		So(string(multi[`messages["en.US"]`]), ShouldEqual, `{"messages":{"en.US":{"title": "Hello"}}}`)
+
+		_, err = Cut(jsonData, `messages["en.US"`)
+		So(err, ShouldEqual, ErrInvalidPath)
+	})
+}
+
+func TestJSONPointerPaths(t *testing.T) {
+	jsonData := []byte(`{
+		"user": {"address": {"city": "Anytown", "zip": "12345"}},
+		"paths": {"/pets": {"get": "list"}, "a~b": 1},
+		"orders": [{"id": 1}, {"id": 2}]
+	}`)
+
+	Convey("测试JSON Pointer路径", t, func() {
+		Convey("解析JSON Pointer", func() {
+			segments, err := parsePointer("/paths/~1pets/a~0b")
+			So(err, ShouldBeNil)
+			So(segments, ShouldResemble, []segment{
+				{kind: pointerSegment, name: "paths"},
+				{kind: pointerSegment, name: "/pets"},
+				{kind: pointerSegment, name: "a~b"},
+			})
+
+			segments, err = parsePointer("#/paths/~1pets/a%20b")
+			So(err, ShouldBeNil)
+			So(segments[2].name, ShouldEqual, "a b")
+
+			segments, err = parsePointer("")
+			So(err, ShouldBeNil)
+			So(len(segments), ShouldEqual, 0)
+
+			for _, pointer := range []string{"user", "/a~2", "/a~"} {
+				_, err = parsePointer(pointer)
+				So(err, ShouldEqual, ErrInvalidPath)
+			}
+		})
+
+		Convey("以斜杠开头的路径自动按JSON Pointer解析", func() {
+			result, err := Cut(jsonData, "/user/address/city")
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"user":{"address":{"city":"Anytown"}}}`)
+
+			result, err = Cut(jsonData, "/paths/~1pets/get")
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"paths":{"/pets":{"get":"list"}}}`)
+
+			result, err = Cut(jsonData, "/orders/1/id")
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"orders":[{"id":2}]}`)
+		})
+
+		Convey("JSON Pointer不支持负索引和前导零", func() {
+			result, err := Cut(jsonData, "/orders/-1")
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{}`)
+
+			result, err = Cut(jsonData, "/orders/01")
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{}`)
+		})
+
+		Convey("显式指定路径语法", func() {
+			rule := NewKeepArrayElementsIfChildValueMatchesRule("orders", "id", float64(2))
+			rule.PathSyntax = PathPointer
+			rule.Path = "/orders"
+			rule.ChildPath = "/id"
+			result, err := CutWithRules(jsonData, []Rule{rule})
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"orders":[{"id":2}]}`)
+
+			rule = NewKeepPathRule("user.address")
+			rule.PathSyntax = PathPointer
+			_, err = CutWithRules(jsonData, []Rule{rule})
+			So(err, ShouldEqual, ErrInvalidPath)
+
+			rule = NewKeepPathRule("/user")
+			rule.PathSyntax = PathDotted
+			result, err = CutWithRules(jsonData, []Rule{rule})
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{}`)
+		})
+
+		Convey("在配置文件中指定路径语法", func() {
+			rule, err := buildRuleFromConfig(RuleConfig{Type: "keep_path", Where: "user", PathSyntax: "pointer"})
+			So(err, ShouldBeNil)
+			So(rule.PathSyntax, ShouldEqual, PathPointer)
+
+			_, err = buildRuleFromConfig(RuleConfig{Type: "keep_path", Where: "user", PathSyntax: "xpath"})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
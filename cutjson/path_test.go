@@ -0,0 +1,183 @@
+package cutjson
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWildcardPaths(t *testing.T) {
+	jsonData, err := os.ReadFile("../examples/rules_example.json")
+	if err != nil {
+		t.Fatalf("无法读取测试文件: %v", err)
+	}
+
+	Convey("测试通配符路径段", t, func() {
+		Convey("保留每个数组元素的指定字段", func() {
+			result, err := Cut(jsonData, "products.*.name")
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"products":[{"name":"Laptop"},{"name":"Smartphone"},{"name":"Headphones"}]}`)
+		})
+
+		Convey("保留对象的所有键", func() {
+			result, err := Cut(jsonData, "settings.*")
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"settings":{"currency":"USD","language":"en-US","timezone":"UTC-5"}}`)
+		})
+
+		Convey("多个通配符", func() {
+			result, err := Cut(jsonData, "orders.*.items.*.productId")
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"orders":[{"items":[{"productId":101},{"productId":103}]},{"items":[{"productId":102}]}]}`)
+		})
+
+		Convey("跳过不包含该字段的元素", func() {
+			data := []byte(`{"items":[{"a":1},{"b":2},{"a":3}]}`)
+			result, err := Cut(data, "items.*.a")
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"items":[{"a":1},{"a":3}]}`)
+
+			result, err = CutWithOptions(data, []Rule{NewKeepPathRule("items.*.a")}, Options{ArrayMode: PreserveArrayPositions})
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"items":[{"a":1},null,{"a":3}]}`)
+		})
+
+		Convey("在条件规则中使用通配符", func() {
+			rules := []Rule{
+				NewKeepParentIfValueMatchesRule("products.*.category", "accessories"),
+			}
+			result, err := CutWithRules(jsonData, rules)
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"products":[{"category":"accessories","id":103,"inStock":true,"name":"Headphones","price":199.99}]}`)
+
+			rules = []Rule{
+				NewKeepArrayElementsIfChildValueMatchesRule("orders", "items.*.productId", float64(102)),
+			}
+			result, err = CutWithRules(jsonData, rules)
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"orders":[{"id":1002,"items":[{"productId":102,"quantity":1}],"status":"pending"}]}`)
+		})
+	})
+}
+
+func TestDescendantPaths(t *testing.T) {
+	jsonData, err := os.ReadFile("../examples/rules_example.json")
+	if err != nil {
+		t.Fatalf("无法读取测试文件: %v", err)
+	}
+
+	Convey("测试任意深度路径段", t, func() {
+		Convey("保留任意深度的字段及其上层结构", func() {
+			expected := `{"orders":[{"items":[{"productId":101},{"productId":103}]},{"items":[{"productId":102}]}],"user":{"purchases":[{"productId":101},{"productId":103}]}}`
+
+			result, err := Cut(jsonData, "**.productId")
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, expected)
+
+			result, err = Cut(jsonData, "..productId")
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, expected)
+		})
+
+		Convey("在指定路径下任意深度查找", func() {
+			result, err := Cut(jsonData, "orders..productId")
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"orders":[{"items":[{"productId":101},{"productId":103}]},{"items":[{"productId":102}]}]}`)
+		})
+
+		Convey("匹配零层", func() {
+			result, err := Cut(jsonData, "user.**.city")
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"user":{"address":{"city":"Anytown"}}}`)
+
+			result, err = Cut(jsonData, "**.settings.currency")
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"settings":{"currency":"USD"}}`)
+		})
+
+		Convey("在条件规则中使用任意深度路径", func() {
+			rules := []Rule{
+				NewKeepArrayElementsIfChildValueMatchesRule("**.items", "quantity", float64(2)),
+			}
+			result, err := CutWithRules(jsonData, rules)
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"orders":[{"items":[{"productId":103,"quantity":2}]}]}`)
+		})
+
+		Convey("没有匹配时不输出任何内容", func() {
+			result, err := Cut(jsonData, "**.missing")
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{}`)
+		})
+	})
+}
+
+func TestSlicePaths(t *testing.T) {
+	jsonData := []byte(`{"items": [0, 1, 2, 3, 4, 5, 6], "orders": [{"id": 1}, {"id": 2}, {"id": 3}, {"id": 4}]}`)
+
+	cut := func(path string) string {
+		result, err := Cut(jsonData, path)
+		So(err, ShouldBeNil)
+		return string(result)
+	}
+
+	Convey("测试数组切片路径段", t, func() {
+		Convey("前N个和后N个元素", func() {
+			So(cut("orders[0:2]"), ShouldEqual, `{"orders":[{"id": 1},{"id": 2}]}`)
+			So(cut("orders[:2].id"), ShouldEqual, `{"orders":[{"id":1},{"id":2}]}`)
+			So(cut("orders[-3:]"), ShouldEqual, `{"orders":[{"id": 2},{"id": 3},{"id": 4}]}`)
+		})
+
+		Convey("步长", func() {
+			So(cut("items[::2]"), ShouldEqual, `{"items":[0,2,4,6]}`)
+			So(cut("items[1:6:2]"), ShouldEqual, `{"items":[1,3,5]}`)
+			So(cut("items[::-3]"), ShouldEqual, `{"items":[0,3,6]}`)
+			So(cut("items[::0]"), ShouldEqual, `{}`)
+		})
+
+		Convey("负数步长只按位置选择元素，结果保持原始顺序", func() {
+			So(cut("orders[::-1].id"), ShouldEqual, `{"orders":[{"id":1},{"id":2},{"id":3},{"id":4}]}`)
+			So(cut("orders[2::-2].id"), ShouldEqual, `{"orders":[{"id":1},{"id":3}]}`)
+
+			var out bytes.Buffer
+			So(CutReader(bytes.NewReader(jsonData), &out, []Rule{NewKeepPathRule("orders[::-1].id")}, Options{}), ShouldBeNil)
+			So(out.String(), ShouldEqual, `{"orders":[{"id":1},{"id":2},{"id":3},{"id":4}]}`)
+		})
+
+		Convey("越界的切片被截断", func() {
+			So(cut("items[5:100]"), ShouldEqual, `{"items":[5,6]}`)
+			So(cut("items[-100:1]"), ShouldEqual, `{"items":[0]}`)
+			So(cut("items[4:2]"), ShouldEqual, `{}`)
+		})
+
+		Convey("JSONPath中的切片", func() {
+			So(cut("$.orders[1:3].id"), ShouldEqual, `{"orders":[{"id":2},{"id":3}]}`)
+			So(cut("$.items[5:,0]"), ShouldEqual, `{"items":[0,5,6]}`)
+		})
+
+		Convey("保持元素位置", func() {
+			result, err := CutWithOptions(jsonData, []Rule{NewKeepPathRule("items[-2:]")}, Options{ArrayMode: PreserveArrayPositions})
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"items":[null,null,null,null,null,5,6]}`)
+		})
+
+		Convey("无效的切片", func() {
+			for _, path := range []string{"items[1:2:3:4]", "items[a:]", "$.items[01:]"} {
+				_, err := Cut(jsonData, path)
+				So(err, ShouldEqual, ErrInvalidPath)
+			}
+		})
+	})
+}
+
+func TestSliceIndices(t *testing.T) {
+	Convey("测试切片索引计算", t, func() {
+		So(sliceBounds{step: 1}.indices(3), ShouldResemble, []int{0, 1, 2})
+		So(sliceBounds{step: -1}.indices(3), ShouldResemble, []int{2, 1, 0})
+		So(sliceBounds{start: 1, hasStart: true, step: -1}.indices(3), ShouldResemble, []int{1, 0})
+		So(sliceBounds{end: -1, hasEnd: true, step: 1}.indices(3), ShouldResemble, []int{0, 1})
+		So(sliceBounds{step: 2}.indices(0), ShouldBeEmpty)
+	})
+}
@@ -0,0 +1,356 @@
+package cutjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// rawValue is a value of the input document kept as its exact source bytes. A container is only
+// split into its children when a path descends into it, so values no path reaches are never decoded
+type rawValue struct {
+	data     []byte
+	children interface{}       // map[string]interface{} or []interface{} of *rawValue once split
+	names    map[string][]byte // the member names of a split object as written in the source
+	order    []string          // the member names of a split object in source order
+}
+
+// cutRaw cuts jsonData when every rule is a plain keep path rule, copying the kept values from the
+// input instead of decoding and re-encoding them; ok is false if the rules or options need the full engine
+func cutRaw(jsonData []byte, rules []Rule, opts Options) (output []byte, ok bool, err error) {
+	if opts.KeepAll || opts.Conflict != ConflictUnion {
+		return nil, false, nil
+	}
+	for _, rule := range rules {
+		if rule.Type != KeepPath {
+			return nil, false, nil
+		}
+	}
+
+	doc, err := parseRawDocument(jsonData)
+	if err != nil {
+		return nil, true, err
+	}
+
+	result := doc.newResult()
+	for _, rule := range rules {
+		if err := doc.keep(result, rule); err != nil && err != ErrPathNotFound {
+			return nil, true, err
+		}
+	}
+
+	output, err = result.output(opts)
+	return output, true, err
+}
+
+// rawDocument is an input document that is cut without decoding it
+type rawDocument struct {
+	root    *rawValue
+	decoded interface{} // the decoded document, once a filter has compared against it
+}
+
+// parseRawDocument checks that jsonData is a single JSON value; validating is much cheaper than
+// decoding and lets the scanner of raw values trust the input
+func parseRawDocument(jsonData []byte) (*rawDocument, error) {
+	if !json.Valid(jsonData) {
+		return nil, ErrInvalidJSON
+	}
+	return &rawDocument{root: &rawValue{data: bytes.Trim(jsonData, " \t\r\n")}}, nil
+}
+
+// newResult returns an empty result for the document
+func (d *rawDocument) newResult() *rawResult {
+	return &rawResult{source: d.root}
+}
+
+// keep adds the values selected by the path of a keep path rule to result; containers the path
+// descends into are scanned once and shared by every later path
+func (d *rawDocument) keep(result *rawResult, rule Rule) error {
+	segments, err := rule.path()
+	if err != nil {
+		return err
+	}
+
+	// Filters comparing against the document root need it decoded
+	document := interface{}(d.root)
+	if segmentsReferenceRoot(segments) {
+		if d.decoded == nil {
+			d.decoded = d.root.decoded()
+		}
+		document = d.decoded
+	}
+
+	matches, err := resolveFrom(document, match{value: d.root}, segments)
+	if err != nil {
+		return err
+	}
+	for _, m := range matches {
+		result.keep(m.steps)
+	}
+	return nil
+}
+
+// split returns the children of a container, scanning it the first time, or nil for a scalar
+func (r *rawValue) split() interface{} {
+	if r.children != nil {
+		return r.children
+	}
+
+	data := r.data
+	switch data[0] {
+	case '{':
+		members := make(map[string]interface{})
+		r.names = make(map[string][]byte)
+		for pos := skipSpace(data, 1); data[pos] != '}'; {
+			end := skipString(data, pos)
+			text := data[pos:end]
+			name := unquoteName(text)
+
+			pos = skipSpace(data, skipSpace(data, end)+1)
+			end = skipValue(data, pos)
+			if _, ok := members[name]; !ok {
+				r.order = append(r.order, name)
+				r.names[name] = text
+			}
+			members[name] = &rawValue{data: data[pos:end]}
+
+			pos = skipSeparator(data, end)
+		}
+		r.children = members
+
+	case '[':
+		elements := make([]interface{}, 0)
+		for pos := skipSpace(data, 1); data[pos] != ']'; {
+			end := skipValue(data, pos)
+			elements = append(elements, &rawValue{data: data[pos:end]})
+			pos = skipSeparator(data, end)
+		}
+		r.children = elements
+	}
+
+	return r.children
+}
+
+// decoded returns the value fully decoded, for filter expressions that look into it
+func (r *rawValue) decoded() interface{} {
+	var v interface{}
+	if err := decode(r.data, &v); err != nil {
+		return nil
+	}
+	return v
+}
+
+// rawChildren returns the children of data if it is a raw value, and data itself otherwise
+func rawChildren(data interface{}) interface{} {
+	if r, ok := data.(*rawValue); ok {
+		return r.split()
+	}
+	return data
+}
+
+// plainValue returns data fully decoded if it is a raw value, and data itself otherwise
+func plainValue(data interface{}) interface{} {
+	if r, ok := data.(*rawValue); ok {
+		return r.decoded()
+	}
+	return data
+}
+
+// skipSpace returns the position of the first non-whitespace byte at or after pos
+func skipSpace(data []byte, pos int) int {
+	for pos < len(data) {
+		switch data[pos] {
+		case ' ', '\t', '\r', '\n':
+			pos++
+		default:
+			return pos
+		}
+	}
+	return pos
+}
+
+// skipSeparator returns the position of the next member after the value ending at pos, or of the
+// closing bracket of the container
+func skipSeparator(data []byte, pos int) int {
+	pos = skipSpace(data, pos)
+	if data[pos] == ',' {
+		pos = skipSpace(data, pos+1)
+	}
+	return pos
+}
+
+// skipString returns the position just after the string starting at pos
+func skipString(data []byte, pos int) int {
+	for i := pos + 1; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(data)
+}
+
+// skipValue returns the position just after the value starting at pos
+func skipValue(data []byte, pos int) int {
+	switch data[pos] {
+	case '"':
+		return skipString(data, pos)
+
+	case '{', '[':
+		depth := 0
+		for i := pos; i < len(data); i++ {
+			switch data[i] {
+			case '"':
+				i = skipString(data, i) - 1
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return i + 1
+				}
+			}
+		}
+		return len(data)
+
+	default:
+		for i := pos; i < len(data); i++ {
+			switch data[i] {
+			case ',', '}', ']', ' ', '\t', '\r', '\n':
+				return i
+			}
+		}
+		return len(data)
+	}
+}
+
+// unquoteName decodes a member name, which only needs the decoder if it contains escapes
+func unquoteName(text []byte) string {
+	if bytes.IndexByte(text, '\\') < 0 {
+		return string(text[1 : len(text)-1])
+	}
+	var name string
+	json.Unmarshal(text, &name)
+	return name
+}
+
+// rawResult is the part of the result at one position of the document: either the source value
+// kept as a whole or the kept children of a source container
+type rawResult struct {
+	source   *rawValue
+	whole    bool
+	members  map[string]*rawResult
+	elements map[int]*rawResult
+}
+
+// keep adds the source value reached by steps to the result; a value kept as a whole already
+// contains everything below it
+func (n *rawResult) keep(steps []step) {
+	for _, s := range steps {
+		if n.whole {
+			return
+		}
+
+		var child *rawResult
+		if s.isIndex {
+			if n.elements == nil {
+				n.elements = make(map[int]*rawResult)
+			}
+			if child = n.elements[s.index]; child == nil {
+				source := n.source.split().([]interface{})[s.index].(*rawValue)
+				child = &rawResult{source: source}
+				n.elements[s.index] = child
+			}
+		} else {
+			if n.members == nil {
+				n.members = make(map[string]*rawResult)
+			}
+			if child = n.members[s.key]; child == nil {
+				source := n.source.split().(map[string]interface{})[s.key].(*rawValue)
+				child = &rawResult{source: source}
+				n.members[s.key] = child
+			}
+		}
+		n = child
+	}
+
+	n.whole = true
+	n.members, n.elements = nil, nil
+}
+
+// output encodes the result laid out according to opts
+func (n *rawResult) output(opts Options) ([]byte, error) {
+	placeholder, err := json.Marshal(opts.Placeholder)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	n.write(&buf, opts, placeholder)
+	return buf.Bytes(), nil
+}
+
+// write appends the result to buf; a position where nothing was kept is written as an empty object
+func (n *rawResult) write(buf *bytes.Buffer, opts Options, placeholder []byte) {
+	if n.whole {
+		buf.Write(n.source.data)
+		return
+	}
+
+	if n.elements != nil {
+		buf.WriteByte('[')
+		if opts.ArrayMode == PreserveArrayPositions {
+			for i := range n.source.split().([]interface{}) {
+				if i > 0 {
+					buf.WriteByte(',')
+				}
+				if child, ok := n.elements[i]; ok {
+					child.write(buf, opts, placeholder)
+				} else {
+					buf.Write(placeholder)
+				}
+			}
+		} else {
+			indices := make([]int, 0, len(n.elements))
+			for i := range n.elements {
+				indices = append(indices, i)
+			}
+			sort.Ints(indices)
+			for i, index := range indices {
+				if i > 0 {
+					buf.WriteByte(',')
+				}
+				n.elements[index].write(buf, opts, placeholder)
+			}
+		}
+		buf.WriteByte(']')
+		return
+	}
+
+	var names []string
+	if opts.PreserveKeyOrder && n.members != nil {
+		for _, name := range n.source.order {
+			if _, ok := n.members[name]; ok {
+				names = append(names, name)
+			}
+		}
+	} else {
+		for name := range n.members {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	buf.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(n.source.names[name])
+		buf.WriteByte(':')
+		n.members[name].write(buf, opts, placeholder)
+	}
+	buf.WriteByte('}')
+}
@@ -0,0 +1,143 @@
+package cutjson
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRawCut(t *testing.T) {
+	jsonData := []byte(` {
+		"zeta": {"b": 1, "a": 2},
+		"price": 1.50,
+		"big": 9007199254740993,
+		"text": "café <b>",
+		"odd\"key": [1, 2],
+		"orders": [
+			{"id": 1, "status": "paid",  "total": 1e2},
+			{"id": 2, "status": "new",   "total": 0.10},
+			{"id": 3, "status": "paid",  "total": 3}
+		],
+		"limit": 2
+	} `)
+
+	cut := func(path string) string {
+		result, err := Cut(jsonData, path)
+		So(err, ShouldBeNil)
+		return string(result)
+	}
+
+	Convey("测试按原始字节保留值", t, func() {
+		Convey("保留值的格式、数字写法和字符串转义", func() {
+			So(cut("zeta"), ShouldEqual, `{"zeta":{"b": 1, "a": 2}}`)
+			So(cut("price"), ShouldEqual, `{"price":1.50}`)
+			So(cut("big"), ShouldEqual, `{"big":9007199254740993}`)
+			So(cut("text"), ShouldEqual, `{"text":"café <b>"}`)
+			So(cut(`["odd\"key"]`), ShouldEqual, `{"odd\"key":[1, 2]}`)
+			So(cut("$"), ShouldEqual, string(jsonData[1:len(jsonData)-1]))
+		})
+
+		Convey("重新构建被选中值外层的对象和数组", func() {
+			So(cut("orders.*.total"), ShouldEqual, `{"orders":[{"total":1e2},{"total":0.10},{"total":3}]}`)
+			So(cut("orders.-1.status"), ShouldEqual, `{"orders":[{"status":"paid"}]}`)
+			So(cut("**.b"), ShouldEqual, `{"zeta":{"b":1}}`)
+			So(cut("$.orders[?@.status == 'paid'].id"), ShouldEqual, `{"orders":[{"id":1},{"id":3}]}`)
+			So(cut("$.orders[?@.id == $.limit]"), ShouldEqual, `{"orders":[{"id": 2, "status": "new",   "total": 0.10}]}`)
+			So(cut("missing"), ShouldEqual, `{}`)
+		})
+
+		Convey("多个规则重叠时合并", func() {
+			result, errs, err := CutMultiple(jsonData, []string{"zeta.a", "orders.1"})
+			So(err, ShouldBeNil)
+			So(errs, ShouldBeEmpty)
+			So(string(result["zeta.a"]), ShouldEqual, `{"zeta":{"a":2}}`)
+			So(string(result["orders.1"]), ShouldEqual, `{"orders":[{"id": 2, "status": "new",   "total": 0.10}]}`)
+
+			output, ok, err := cutRaw(jsonData, []Rule{NewKeepPathRule("orders.0.id"), NewKeepPathRule("orders.0"), NewKeepPathRule("zeta.b")},
+				Options{ArrayMode: PreserveArrayPositions, PreserveKeyOrder: true})
+			So(ok, ShouldBeTrue)
+			So(err, ShouldBeNil)
+			So(string(output), ShouldEqual, `{"zeta":{"b":1},"orders":[{"id": 1, "status": "paid",  "total": 1e2},null,null]}`)
+		})
+
+		Convey("结果与重新编码的结果等价", func() {
+			for _, path := range []string{"orders[::2].id", "$..status", "orders[1:]", "price", "zeta.*", "$.orders[?@.total > 1]"} {
+				raw := cut(path)
+				decoded, err := CutWithRules(jsonData, []Rule{NewKeepPathRule(path)})
+				So(err, ShouldBeNil)
+				So(string(normalizeJSON([]byte(raw))), ShouldEqual, string(normalizeJSON(decoded)))
+			}
+		})
+
+		Convey("其他规则使用完整的裁剪流程", func() {
+			_, ok, err := cutRaw(jsonData, []Rule{NewKeepPathRule("zeta"), NewDropPathRule("zeta.a")}, Options{})
+			So(ok, ShouldBeFalse)
+			So(err, ShouldBeNil)
+
+			_, ok, _ = cutRaw(jsonData, []Rule{NewKeepPathRule("zeta")}, Options{KeepAll: true})
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("错误处理", func() {
+			for _, input := range []string{``, `{"a":1} x`, `{"a":`, `[1,2]]`} {
+				_, err := Cut([]byte(input), "a")
+				So(err, ShouldEqual, ErrInvalidJSON)
+			}
+
+			_, err := Cut(jsonData, "orders.name")
+			So(err, ShouldEqual, ErrInvalidPath)
+
+			_, err = Cut(jsonData, "orders[")
+			So(err, ShouldEqual, ErrInvalidPath)
+		})
+	})
+}
+
+func TestCutMultiple(t *testing.T) {
+	jsonData, err := os.ReadFile("../examples/rules_example.json")
+	if err != nil {
+		t.Fatalf("无法读取测试文件: %v", err)
+	}
+
+	Convey("测试一次提取多个路径", t, func() {
+		Convey("每个路径单独输出结果", func() {
+			paths := []string{"user.name", "orders.*.id", "**.city", "$.products[?@.price < 1000].name"}
+			results, errs, err := CutMultiple(jsonData, paths)
+			So(err, ShouldBeNil)
+			So(errs, ShouldBeEmpty)
+			So(results, ShouldHaveLength, len(paths))
+
+			for _, path := range paths {
+				expected, err := Cut(jsonData, path)
+				So(err, ShouldBeNil)
+				So(string(results[path]), ShouldEqual, string(expected))
+			}
+		})
+
+		Convey("区分不存在的路径和无效的路径", func() {
+			results, errs, err := CutMultiple(jsonData, []string{"user.name", "user.missing", "orders.name", "orders[", "/user/~2"})
+			So(err, ShouldBeNil)
+			So(errs, ShouldHaveLength, 4)
+
+			So(string(results["user.name"]), ShouldEqual, `{"user":{"name":"John Doe"}}`)
+			So(errs["user.name"], ShouldBeNil)
+
+			So(string(results["user.missing"]), ShouldEqual, `{}`)
+			So(errs["user.missing"], ShouldEqual, ErrPathNotFound)
+
+			for _, path := range []string{"orders.name", "orders[", "/user/~2"} {
+				So(results, ShouldContainKey, path)
+				So(results[path], ShouldBeNil)
+				So(errs[path], ShouldEqual, ErrInvalidPath)
+			}
+		})
+
+		Convey("输入无效时返回错误", func() {
+			results, errs, err := CutMultiple([]byte(`{"user":`), []string{"user"})
+			So(err, ShouldEqual, ErrInvalidJSON)
+			So(results, ShouldBeNil)
+			So(errs, ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,285 @@
+package cutjson
+
+import "sort"
+
+// ArrayMode controls how the kept elements of an array are laid out in the output
+type ArrayMode int
+
+const (
+	// CompactArrays 只输出被保留的数组元素，按原始顺序紧凑排列
+	CompactArrays ArrayMode = iota
+	// PreserveArrayPositions 保持元素在原数组中的位置，未保留的位置使用占位值填充
+	PreserveArrayPositions
+)
+
+// Options controls how the result of a cut is assembled
+type Options struct {
+	ArrayMode        ArrayMode         // 数组输出方式
+	Placeholder      interface{}       // 未保留数组位置的占位值（用于PreserveArrayPositions）
+	Conflict         ConflictPolicy    // 多个规则保留重叠内容时的合并策略
+	KeepAll          bool              // 从完整文档开始裁剪，保留规则按合并策略合并，移除规则从中删除内容
+	PreserveKeyOrder bool              // 输出对象的键保持源文档中的顺序，默认按字母顺序排列
+	InvalidLines     InvalidLinePolicy // CutStream遇到不是有效JSON的行时的处理方式
+}
+
+// object is a partially kept JSON object in the result
+type object map[string]interface{}
+
+// array is a partially kept JSON array in the result; elements remember their source index
+type array struct {
+	length int
+	items  map[int]interface{}
+}
+
+func newArray(length int) *array {
+	return &array{length: length, items: make(map[int]interface{})}
+}
+
+// resultBuilder accumulates the parts of the source document kept by the rules
+type resultBuilder struct {
+	source  interface{}
+	root    interface{}
+	present bool // the result has a root value; root alone cannot tell an empty result from a JSON null
+	policy  ConflictPolicy
+}
+
+func newResultBuilder(source interface{}, policy ConflictPolicy) *resultBuilder {
+	return &resultBuilder{source: source, policy: policy}
+}
+
+// keep adds value to the result at the position described by steps
+func (b *resultBuilder) keep(steps []step, value interface{}) {
+	b.root = place(b.root, b.present, b.source, steps, value, b.policy)
+	b.present = true
+}
+
+// keepDocument starts the result as the whole source document
+func (b *resultBuilder) keepDocument() {
+	b.root, b.present = b.source, true
+}
+
+// drop removes the value at the position described by steps from the result
+func (b *resultBuilder) drop(steps []step) {
+	if !b.present {
+		return
+	}
+	if len(steps) == 0 {
+		b.root, b.present = nil, false
+		return
+	}
+	b.root = remove(b.root, steps)
+}
+
+// replace substitutes value for the value at the position described by steps, if the result has one
+func (b *resultBuilder) replace(steps []step, value interface{}) {
+	if !b.present {
+		return
+	}
+	if len(steps) == 0 {
+		b.root = value
+		return
+	}
+	b.root = rewrite(b.root, steps, func(interface{}) (interface{}, bool) {
+		return value, true
+	})
+}
+
+// intersect narrows the result down to the parts also kept by other
+func (b *resultBuilder) intersect(other *resultBuilder) {
+	if !b.present || !other.present {
+		b.root, b.present = nil, false
+		return
+	}
+	b.root, _ = intersectValues(b.root, other.root)
+}
+
+// build converts the accumulated result into plain JSON values; objects follow order when it is not nil
+func (b *resultBuilder) build(opts Options, order keyOrder) interface{} {
+	if !b.present {
+		return map[string]interface{}{}
+	}
+	f := &finalizer{opts: opts, order: order}
+	return f.finalize(b.root, b.source)
+}
+
+// expand turns a source container kept as a whole into an equivalent result container,
+// so that it can be modified without touching the source document
+func expand(v interface{}) interface{} {
+	switch n := v.(type) {
+	case map[string]interface{}:
+		obj := make(object, len(n))
+		for k, child := range n {
+			obj[k] = child
+		}
+		return obj
+
+	case []interface{}:
+		arr := newArray(len(n))
+		for i, child := range n {
+			arr.items[i] = child
+		}
+		return arr
+
+	default:
+		return v
+	}
+}
+
+// remove deletes the value at steps below v
+func remove(v interface{}, steps []step) interface{} {
+	return rewrite(v, steps, func(interface{}) (interface{}, bool) {
+		return nil, false
+	})
+}
+
+// rewrite replaces the value at steps below v with the result of edit, or deletes it when edit
+// returns false, expanding source containers on the way so that the source document is left
+// untouched; v is returned unchanged when it has no value at steps
+func rewrite(v interface{}, steps []step, edit func(interface{}) (interface{}, bool)) interface{} {
+	s := steps[0]
+
+	switch n := v.(type) {
+	case map[string]interface{}, object:
+		if s.isIndex {
+			return v
+		}
+		if _, found := childOf(n, s); !found {
+			return v
+		}
+		obj := expand(n).(object)
+		if len(steps) > 1 {
+			obj[s.key] = rewrite(obj[s.key], steps[1:], edit)
+		} else if value, ok := edit(obj[s.key]); ok {
+			obj[s.key] = value
+		} else {
+			delete(obj, s.key)
+		}
+		return obj
+
+	case []interface{}, *array:
+		if !s.isIndex {
+			return v
+		}
+		if _, found := childOf(n, s); !found {
+			return v
+		}
+		arr := expand(n).(*array)
+		if len(steps) > 1 {
+			arr.items[s.index] = rewrite(arr.items[s.index], steps[1:], edit)
+		} else if value, ok := edit(arr.items[s.index]); ok {
+			arr.items[s.index] = value
+		} else {
+			delete(arr.items, s.index)
+		}
+		return arr
+
+	default:
+		return v
+	}
+}
+
+// childOf returns the child of a source or result container reached by s
+func childOf(v interface{}, s step) (interface{}, bool) {
+	switch n := v.(type) {
+	case map[string]interface{}:
+		child, found := n[s.key]
+		return child, found
+	case object:
+		child, found := n[s.key]
+		return child, found
+	case []interface{}:
+		if s.index < 0 || s.index >= len(n) {
+			return nil, false
+		}
+		return n[s.index], true
+	case *array:
+		child, found := n.items[s.index]
+		return child, found
+	default:
+		return nil, false
+	}
+}
+
+// finalizer converts result containers into plain JSON values; when order is set, objects keep
+// the member order of the source document
+type finalizer struct {
+	opts  Options
+	order keyOrder
+}
+
+// finalize converts v, found at the same position as src in the source document
+func (f *finalizer) finalize(v interface{}, src interface{}) interface{} {
+	switch n := v.(type) {
+	case object:
+		srcObject, _ := src.(map[string]interface{})
+		out := make(map[string]interface{}, len(n))
+		for k, child := range n {
+			out[k] = f.finalize(child, srcObject[k])
+		}
+		return f.ordered(out, srcObject)
+
+	case *array:
+		srcArray, _ := src.([]interface{})
+		srcAt := func(i int) interface{} {
+			if i < len(srcArray) {
+				return srcArray[i]
+			}
+			return nil
+		}
+
+		if f.opts.ArrayMode == PreserveArrayPositions {
+			out := make([]interface{}, n.length)
+			for i := range out {
+				out[i] = f.opts.Placeholder
+			}
+			for i, child := range n.items {
+				out[i] = f.finalize(child, srcAt(i))
+			}
+			return out
+		}
+
+		indices := make([]int, 0, len(n.items))
+		for i := range n.items {
+			indices = append(indices, i)
+		}
+		sort.Ints(indices)
+
+		out := make([]interface{}, 0, len(indices))
+		for _, i := range indices {
+			out = append(out, f.finalize(n.items[i], srcAt(i)))
+		}
+		return out
+
+	case map[string]interface{}:
+		// A source object kept as a whole only needs rebuilding to keep its member order
+		if f.order == nil {
+			return n
+		}
+		out := make(map[string]interface{}, len(n))
+		for k, child := range n {
+			out[k] = f.finalize(child, child)
+		}
+		return f.ordered(out, n)
+
+	case []interface{}:
+		if f.order == nil {
+			return n
+		}
+		out := make([]interface{}, len(n))
+		for i, child := range n {
+			out[i] = f.finalize(child, child)
+		}
+		return out
+
+	default:
+		return v
+	}
+}
+
+// ordered wraps out in an orderedObject following the member order of src, if key order is preserved
+func (f *finalizer) ordered(out map[string]interface{}, src map[string]interface{}) interface{} {
+	if f.order == nil {
+		return out
+	}
+	return newOrderedObject(out, f.order.keys(src))
+}
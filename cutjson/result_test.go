@@ -0,0 +1,305 @@
+package cutjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestArrayIndexPaths(t *testing.T) {
+	jsonData := []byte(`{
+		"orders": [
+			{"id": 1, "status": "shipped"},
+			{"id": 2, "status": "pending"},
+			{"id": 3, "status": "shipped"}
+		],
+		"matrix": [[1, 2], [3, 4, 5]]
+	}`)
+
+	Convey("测试通过数组索引保留路径", t, func() {
+		Convey("索引路径生成真实的数组", func() {
+			result, err := Cut(jsonData, "orders.0.id")
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"orders":[{"id":1}]}`)
+		})
+
+		Convey("负索引按原数组长度解析", func() {
+			result, err := Cut(jsonData, "orders.-1.status")
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"orders":[{"status":"shipped"}]}`)
+		})
+
+		Convey("嵌套数组", func() {
+			result, err := Cut(jsonData, "matrix.1.2")
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"matrix":[[5]]}`)
+		})
+
+		Convey("多个索引按原始顺序紧凑排列", func() {
+			rules := []Rule{
+				NewKeepPathRule("orders.2.id"),
+				NewKeepPathRule("orders.0.id"),
+			}
+			result, err := CutWithRules(jsonData, rules)
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"orders":[{"id":1},{"id":3}]}`)
+		})
+
+		Convey("保持元素位置并使用占位值", func() {
+			rules := []Rule{
+				NewKeepPathRule("orders.1.id"),
+				NewKeepPathRule("matrix.0.1"),
+			}
+
+			result, err := CutWithOptions(jsonData, rules, Options{ArrayMode: PreserveArrayPositions})
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"matrix":[[null,2],null],"orders":[null,{"id":2},null]}`)
+
+			result, err = CutWithOptions(jsonData, rules, Options{ArrayMode: PreserveArrayPositions, Placeholder: "-"})
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"matrix":[["-",2],"-"],"orders":["-",{"id":2},"-"]}`)
+		})
+
+		Convey("数组过滤规则保持元素位置", func() {
+			rules := []Rule{
+				NewKeepArrayElementsIfChildValueMatchesRule("orders", "status", "shipped"),
+			}
+			result, err := CutWithOptions(jsonData, rules, Options{ArrayMode: PreserveArrayPositions})
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"orders":[{"id":1,"status":"shipped"},null,{"id":3,"status":"shipped"}]}`)
+		})
+
+		Convey("越界索引不输出任何内容", func() {
+			result, err := Cut(jsonData, "orders.5.id")
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{}`)
+		})
+	})
+}
+
+func TestDropRules(t *testing.T) {
+	jsonData := []byte(`{
+		"user": {"name": "John Doe", "password": "secret", "token": "abc"},
+		"sessions": [
+			{"id": 1, "token": "t1"},
+			{"id": 2, "token": "t2"},
+			{"id": 3, "token": "t3"}
+		]
+	}`)
+
+	cut := func(rules []Rule, opts Options) string {
+		result, err := CutWithOptions(jsonData, rules, opts)
+		So(err, ShouldBeNil)
+		return string(result)
+	}
+
+	Convey("测试移除路径规则", t, func() {
+		Convey("只有移除规则时从完整文档开始", func() {
+			rules := []Rule{NewDropPathRule("user.password"), NewDropPathRule("**.token")}
+			So(cut(rules, Options{}), ShouldEqual, `{"sessions":[{"id":1},{"id":2},{"id":3}],"user":{"name":"John Doe"}}`)
+		})
+
+		Convey("移除规则在所有保留规则之后应用", func() {
+			rules := []Rule{NewDropPathRule("user.token"), NewKeepPathRule("user")}
+			So(cut(rules, Options{}), ShouldEqual, `{"user":{"name":"John Doe","password":"secret"}}`)
+		})
+
+		Convey("移除数组元素", func() {
+			rules := []Rule{NewKeepPathRule("sessions"), NewDropPathRule("sessions.1")}
+			So(cut(rules, Options{}), ShouldEqual, `{"sessions":[{"id":1,"token":"t1"},{"id":3,"token":"t3"}]}`)
+			So(cut(rules, Options{ArrayMode: PreserveArrayPositions}), ShouldEqual,
+				`{"sessions":[{"id":1,"token":"t1"},null,{"id":3,"token":"t3"}]}`)
+		})
+
+		Convey("KeepAll模式下保留规则不改变结果", func() {
+			rules := []Rule{NewKeepPathRule("user.name"), NewDropPathRule("sessions")}
+			So(cut(rules, Options{KeepAll: true}), ShouldEqual, `{"user":{"name":"John Doe","password":"secret","token":"abc"}}`)
+		})
+
+		Convey("KeepAll模式与交集策略只保留规则选中的内容", func() {
+			rules := []Rule{NewKeepPathRule("user"), NewDropPathRule("user.password")}
+			So(cut(rules, Options{KeepAll: true, Conflict: ConflictIntersection}), ShouldEqual, `{"user":{"name":"John Doe","token":"abc"}}`)
+		})
+
+		Convey("移除不存在的路径不报错", func() {
+			So(cut([]Rule{NewDropPathRule("missing")}, Options{}), ShouldEqual, string(normalizeJSON(jsonData)))
+		})
+
+		Convey("移除整个文档", func() {
+			So(cut([]Rule{NewDropPathRule("$")}, Options{}), ShouldEqual, `{}`)
+		})
+
+		Convey("从null文档开始时结果仍然是null", func() {
+			ruleSets := [][]Rule{
+				{NewDropPathRule("missing")},
+				{NewMaskPathRule("missing", Mask{})},
+				{NewKeepPathRule("$")},
+			}
+			for _, input := range []string{`null`, `"x"`} {
+				for _, rules := range ruleSets {
+					result, err := CutWithOptions([]byte(input), rules, Options{})
+					So(err, ShouldBeNil)
+					So(string(result), ShouldEqual, input)
+
+					var out bytes.Buffer
+					So(CutReader(strings.NewReader(input), &out, rules, Options{}), ShouldBeNil)
+					So(out.String(), ShouldEqual, input)
+				}
+
+				result, err := CutWithOptions([]byte(input), []Rule{NewKeepPathRule("missing")}, Options{KeepAll: true})
+				So(err, ShouldBeNil)
+				So(string(result), ShouldEqual, input)
+
+				result, err = CutWithOptions([]byte(input), []Rule{NewDropPathRule("$")}, Options{})
+				So(err, ShouldBeNil)
+				So(string(result), ShouldEqual, `{}`)
+			}
+		})
+
+		Convey("源文档不会被修改", func() {
+			var data interface{}
+			So(json.Unmarshal(jsonData, &data), ShouldBeNil)
+			_, err := applyRules(data, nil, []Rule{NewDropPathRule("user.password"), NewDropPathRule("sessions.0.token")}, Options{})
+			So(err, ShouldBeNil)
+			So(data.(map[string]interface{})["user"], ShouldContainKey, "password")
+			So(data.(map[string]interface{})["sessions"].([]interface{})[0], ShouldContainKey, "token")
+		})
+	})
+}
+
+func TestDropArrayElements(t *testing.T) {
+	jsonData := []byte(`{
+		"user": {"name": "John Doe"},
+		"orders": [
+			{"id": 1, "status": "shipped"},
+			{"id": 2, "status": "cancelled"},
+			{"id": 3, "status": "pending"},
+			{"id": 4, "status": "cancelled"}
+		]
+	}`)
+
+	cut := func(rules []Rule, opts Options) string {
+		result, err := CutWithOptions(jsonData, rules, opts)
+		So(err, ShouldBeNil)
+		return string(result)
+	}
+
+	Convey("测试移除数组中满足条件的元素", t, func() {
+		Convey("保留文档的其余部分", func() {
+			rules := []Rule{NewDropArrayElementsIfChildValueMatchesRule("orders", "status", "cancelled")}
+			So(cut(rules, Options{}), ShouldEqual,
+				`{"orders":[{"id":1,"status":"shipped"},{"id":3,"status":"pending"}],"user":{"name":"John Doe"}}`)
+		})
+
+		Convey("与保留规则组合", func() {
+			rules := []Rule{
+				NewKeepPathRule("orders"),
+				NewDropArrayElementsIfChildValueComparesRule("orders", "id", OpGreaterThan, 2),
+			}
+			So(cut(rules, Options{}), ShouldEqual, `{"orders":[{"id":1,"status":"shipped"},{"id":2,"status":"cancelled"}]}`)
+			So(cut(rules, Options{ArrayMode: PreserveArrayPositions}), ShouldEqual,
+				`{"orders":[{"id":1,"status":"shipped"},{"id":2,"status":"cancelled"},null,null]}`)
+		})
+
+		Convey("使用组合条件", func() {
+			rules := []Rule{NewDropArrayElementsIfConditionRule("orders", Any(
+				Where("status", OpEquals, "cancelled"),
+				Where("id", OpEquals, 1),
+			))}
+			So(cut(rules, Options{}), ShouldEqual, `{"orders":[{"id":3,"status":"pending"}],"user":{"name":"John Doe"}}`)
+		})
+	})
+}
+
+func TestArrayElementFields(t *testing.T) {
+	jsonData := []byte(`{
+		"products": [
+			{"id": 101, "category": "electronics", "price": 999, "specs": {"cpu": "i7", "ram": 16}},
+			{"id": 102, "category": "accessories", "price": 25},
+			{"id": 103, "category": "electronics", "price": 499, "specs": {"cpu": "i5", "ram": 8}}
+		]
+	}`)
+
+	cut := func(rules []Rule, opts Options) string {
+		result, err := CutWithOptions(jsonData, rules, opts)
+		So(err, ShouldBeNil)
+		return string(result)
+	}
+
+	Convey("测试裁剪数组中匹配的元素", t, func() {
+		rule := NewKeepArrayElementsIfChildValueMatchesRule("products", "category", "electronics")
+
+		Convey("只保留指定的子路径", func() {
+			rule.Fields = []string{"id", "price"}
+			So(cut([]Rule{rule}, Options{}), ShouldEqual, `{"products":[{"id":101,"price":999},{"id":103,"price":499}]}`)
+		})
+
+		Convey("子路径可以是嵌套路径", func() {
+			rule.Fields = []string{"id", "specs.ram"}
+			So(cut([]Rule{rule}, Options{}), ShouldEqual,
+				`{"products":[{"id":101,"specs":{"ram":16}},{"id":103,"specs":{"ram":8}}]}`)
+		})
+
+		Convey("元素中不存在的子路径被忽略", func() {
+			rule.Fields = []string{"missing"}
+			So(cut([]Rule{rule}, Options{ArrayMode: PreserveArrayPositions}), ShouldEqual, `{"products":[{},null,{}]}`)
+		})
+
+		Convey("与其他规则合并", func() {
+			rule.Fields = []string{"id"}
+			rules := []Rule{rule, NewKeepPathRule("products.0.price")}
+			So(cut(rules, Options{}), ShouldEqual, `{"products":[{"id":101,"price":999},{"id":103}]}`)
+		})
+	})
+}
+
+func TestPreserveKeyOrder(t *testing.T) {
+	jsonData := []byte(`{
+		"zeta": 1,
+		"user": {"name": "John Doe", "email": "john@example.com", "age": 30},
+		"items": [{"qty": 1, "id": 7}, {"qty": 2, "id": 8}],
+		"alpha": {"b": true, "a": false}
+	}`)
+
+	cut := func(rules []Rule, opts Options) string {
+		opts.PreserveKeyOrder = true
+		result, err := CutWithOptions(jsonData, rules, opts)
+		So(err, ShouldBeNil)
+		return string(result)
+	}
+
+	Convey("测试保持源文档中键的顺序", t, func() {
+		Convey("保留的部分按源文档顺序输出", func() {
+			rules := []Rule{NewKeepPathRule("alpha"), NewKeepPathRule("user.age"), NewKeepPathRule("user.name"), NewKeepPathRule("zeta")}
+			So(cut(rules, Options{}), ShouldEqual, `{"zeta":1,"user":{"name":"John Doe","age":30},"alpha":{"b":true,"a":false}}`)
+		})
+
+		Convey("数组元素中的对象也保持顺序", func() {
+			So(cut([]Rule{NewKeepPathRule("items.1")}, Options{}), ShouldEqual, `{"items":[{"qty":2,"id":8}]}`)
+			So(cut([]Rule{NewKeepPathRule("items.1")}, Options{ArrayMode: PreserveArrayPositions}), ShouldEqual,
+				`{"items":[null,{"qty":2,"id":8}]}`)
+		})
+
+		Convey("从完整文档开始移除和替换", func() {
+			rules := []Rule{NewDropPathRule("items"), NewMaskPathRule("user.email", Mask{})}
+			So(cut(rules, Options{}), ShouldEqual,
+				`{"zeta":1,"user":{"name":"John Doe","email":"***","age":30},"alpha":{"b":true,"a":false}}`)
+		})
+
+		Convey("默认按字母顺序输出", func() {
+			result, err := CutWithRules(jsonData, []Rule{NewKeepPathRule("user")})
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"user":{"age":30,"email":"john@example.com","name":"John Doe"}}`)
+		})
+
+		Convey("无效的JSON", func() {
+			for _, input := range []string{`{"a":1} x`, `{"a":}`, `[1,2`} {
+				_, err := CutWithOptions([]byte(input), nil, Options{PreserveKeyOrder: true})
+				So(err, ShouldEqual, ErrInvalidJSON)
+			}
+		})
+	})
+}
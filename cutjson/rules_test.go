@@ -74,6 +74,36 @@ func TestRules(t *testing.T) {
 			So(userObj, ShouldNotContainKey, "age")
 		})
 
+		Convey("规则2: 通过任意深度或通配符到达的父路径可以是数组", func() {
+			data := []byte(`{"a":{"name":"x"},"b":[1,2],"c":[{"name":"x"},{"name":"y"}]}`)
+
+			for _, path := range []string{"**.name", "..name", "$..name"} {
+				result, err := CutWithRules(data, []Rule{NewKeepParentIfValueMatchesRule(path, "x")})
+				So(err, ShouldBeNil)
+				So(string(result), ShouldEqual, `{"a":{"name":"x"},"c":[{"name":"x"}]}`)
+			}
+
+			result, err := CutWithRules(data, []Rule{NewKeepParentIfValueMatchesRule("*.name", "x")})
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"a":{"name":"x"}}`)
+
+			// A name meeting an array reached by an explicit path is still an error
+			_, err = CutWithRules(data, []Rule{NewKeepParentIfValueMatchesRule("b.name", "x")})
+			So(err, ShouldEqual, ErrInvalidPath)
+		})
+
+		Convey("规则2: 顶层字段只保留自身的值匹配的字段", func() {
+			data := []byte(`{"a":{"name":"x"},"b":[1,2],"s":"y","t":"z"}`)
+
+			result, err := CutWithRules(data, []Rule{NewKeepParentIfValueMatchesRule("*", "y")})
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"s":"y"}`)
+
+			result, err = CutWithRules(data, []Rule{NewKeepParentIfValueComparesRule("*", OpNotEquals, "y")})
+			So(err, ShouldBeNil)
+			So(string(result), ShouldEqual, `{"a":{"name":"x"},"b":[1,2],"t":"z"}`)
+		})
+
 		Convey("规则3: 保留数组中满足条件的元素", func() {
 			rules := []Rule{
 				NewKeepArrayElementsIfChildValueMatchesRule("products", "category", "electronics"),
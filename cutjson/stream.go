@@ -0,0 +1,936 @@
+package cutjson
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+)
+
+// CutReader cuts the JSON document read from r according to the rules and writes the result to w.
+// Unlike CutWithOptions it never decodes the whole document: the input is read token by token,
+// subtrees no rule can select are skipped, and only the values a rule has to look at as a whole
+// are buffered, such as one array element while its condition is evaluated or a value being masked.
+// A member whose name matches a FieldMask field name only ignoring case and underscores is buffered
+// together with the members after it, to see whether the object also has the exact name.
+// Object members are written in source order, as with Options.PreserveKeyOrder, and a member name
+// that is repeated in the source is written each time. Filters that refer to the document root
+// with $ and conflict policies other than ConflictUnion need the whole document, so with them the
+// input is read completely and cut with CutWithOptions. Output already written when an error is
+// found is not retracted
+func CutReader(r io.Reader, w io.Writer, rules []Rule, opts Options) error {
+	c, err := newStreamCutter(rules)
+	if err != nil {
+		return err
+	}
+
+	if c.needsDocument || opts.Conflict != ConflictUnion {
+		jsonData, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		opts.PreserveKeyOrder = true
+		result, err := CutWithOptions(jsonData, rules, opts)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(result)
+		return err
+	}
+
+	placeholder, err := json.Marshal(opts.Placeholder)
+	if err != nil {
+		return err
+	}
+	c.out = &streamWriter{
+		w:           bufio.NewWriter(w),
+		preserve:    opts.ArrayMode == PreserveArrayPositions,
+		placeholder: placeholder,
+	}
+
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	if _, err := c.cut(&streamSource{dec: dec}, c.items, c.keepAll || opts.KeepAll, slot{}, false); err != nil {
+		return err
+	}
+
+	// Only whitespace may follow the document
+	if _, err := dec.Token(); err != io.EOF {
+		return ErrInvalidJSON
+	}
+
+	// Nothing kept, or the whole document dropped, leaves an empty object as with CutWithOptions
+	if !c.out.started {
+		c.out.w.WriteString("{}")
+	}
+	return c.out.w.Flush()
+}
+
+// itemKind says what happens when a stream item has matched all of its segments
+type itemKind int
+
+const (
+	// itemKeep 保留匹配的值
+	itemKeep itemKind = iota
+	// itemDrop 移除匹配的值
+	itemDrop
+	// itemMask 替换匹配的值
+	itemMask
+	// itemParent 最后一段路径的值满足比较条件时保留匹配的值（规则2）
+	itemParent
+	// itemTest 匹配的值本身满足比较条件时保留该值
+	itemTest
+	// itemCondition 匹配的值满足组合条件时保留该值（带条件的规则2）
+	itemCondition
+	// itemKeepElements 保留匹配数组中满足条件的元素（规则3）
+	itemKeepElements
+	// itemDropElements 移除匹配数组中满足条件的元素（规则6）
+	itemDropElements
+)
+
+// streamRule is a rule compiled for CutReader
+type streamRule struct {
+	rule    Rule
+	cmp     *comparison
+	cond    *condition  // condition tree of rule type 2, or the element condition of rule types 3 and 6
+	last    []segment   // last path segment of rule type 2, evaluated relative to each parent
+	lenient bool        // the parents of rule type 2 are reached through a wildcard or descendant segment
+	fields  [][]segment // field paths the elements kept by rule type 3 are projected onto
+}
+
+// streamItem tracks how far a rule's path has matched on the way from the root to a value;
+// rest holds the segments still to be matched below it
+type streamItem struct {
+	kind    itemKind
+	rule    *streamRule
+	rest    []segment
+	lenient bool // reached through a descendant segment, so a name meeting an array is not an error
+}
+
+// streamCutter holds the compiled rules and the output of a CutReader call
+type streamCutter struct {
+	items         []streamItem // items starting at the document root
+	keepAll       bool         // the result starts as the whole document
+	needsDocument bool         // a filter refers to the document root, which cannot be streamed
+	out           *streamWriter
+}
+
+// newStreamCutter compiles the rules into the items that start at the document root
+func newStreamCutter(rules []Rule) (*streamCutter, error) {
+	c := &streamCutter{}
+	keeps, others := 0, 0
+
+	for _, rule := range rules {
+		segments, err := rule.path()
+		if err != nil {
+			return nil, err
+		}
+
+		sr := &streamRule{rule: rule}
+		item := streamItem{rule: sr, rest: segments}
+
+		switch rule.Type {
+		case KeepPath:
+			item.kind = itemKeep
+
+		case KeepParentIfValueMatches:
+			if rule.Condition != nil {
+				if sr.cond, err = rule.condition(); err != nil {
+					return nil, err
+				}
+				item.kind = itemCondition
+				break
+			}
+			if sr.cmp, err = rule.comparison(); err != nil {
+				return nil, err
+			}
+			// A path without segments tests the whole document, which has no parent
+			if len(segments) == 0 {
+				item.kind = itemTest
+				break
+			}
+			item.kind = itemParent
+			item.rest, sr.last = segments[:len(segments)-1], segments[len(segments)-1:]
+			sr.lenient = selectsUnnamedChildren(item.rest)
+
+		case KeepArrayElementsIfChildValueMatches, DropArrayElementsIfChildValueMatches:
+			if sr.cond, err = rule.elementCondition(); err != nil {
+				return nil, err
+			}
+			item.kind = itemDropElements
+			if rule.Type == KeepArrayElementsIfChildValueMatches {
+				item.kind = itemKeepElements
+				if sr.fields, err = rule.fields(); err != nil {
+					return nil, err
+				}
+			}
+
+		case DropPath:
+			item.kind = itemDrop
+
+		case MaskPath:
+			item.kind = itemMask
+
+		default:
+			return nil, ErrInvalidRule
+		}
+
+		switch item.kind {
+		case itemDrop, itemMask, itemDropElements:
+			others++
+		default:
+			keeps++
+		}
+
+		c.needsDocument = c.needsDocument || segmentsReferenceRoot(segments) || segmentsReferenceRoot(sr.last) ||
+			conditionReferencesRoot(sr.cond)
+		for _, field := range sr.fields {
+			c.needsDocument = c.needsDocument || segmentsReferenceRoot(field)
+		}
+
+		c.items = append(c.items, item)
+	}
+
+	c.keepAll = keeps == 0 && others > 0
+	return c, nil
+}
+
+// cut processes one value: it works out which rules apply to it, buffers it if one of them has to
+// look at it as a whole, and writes what is kept of it at the slot. kept is set when the value is
+// kept as a whole by an enclosing rule, and projected when it is an element projected onto fields
+// by rule type 3, which is kept as an empty object if none of the fields are found. The result
+// reports whether the value is part of the result before drop rules are applied, which decides
+// whether its parent is
+func (c *streamCutter) cut(src *streamSource, items []streamItem, kept bool, at slot, projected bool) (bool, error) {
+	items = expandDescendants(items)
+	if len(items) == 0 && !kept && !projected {
+		return false, src.skip()
+	}
+
+	var pending, elements, tests []streamItem
+	var mask *Mask
+	dropped, buffer := false, false
+
+	for _, it := range items {
+		if len(it.rest) > 0 {
+			buffer = buffer || needsLength(it.rest[0])
+			pending = append(pending, it)
+			continue
+		}
+
+		switch it.kind {
+		case itemKeep:
+			kept = true
+		case itemDrop:
+			dropped = true
+		case itemMask:
+			mask = &it.rule.rule.Mask
+			buffer = true
+		case itemKeepElements, itemDropElements:
+			elements = append(elements, it)
+		case itemParent:
+			// The root has no parent, so every child below it decides on its own whether it is kept
+			if at.parent == nil {
+				pending = append(pending, streamItem{kind: itemTest, rule: it.rule, rest: it.rule.last, lenient: it.rule.lenient})
+				continue
+			}
+			tests = append(tests, it)
+			buffer = true
+		default:
+			tests = append(tests, it)
+			buffer = true
+		}
+	}
+
+	if buffer {
+		if err := src.materialize(); err != nil {
+			return false, err
+		}
+	}
+
+	for _, it := range tests {
+		switch it.kind {
+		case itemTest:
+			kept = kept || it.rule.cmp.match([]interface{}{src.value})
+
+		case itemCondition:
+			kept = kept || it.rule.cond.test(src.value, match{value: src.value})
+
+		case itemParent:
+			children, ok, err := parentChildren(src.value, match{value: src.value}, it.rule.last, it.rule.lenient)
+			if err != nil {
+				return false, err
+			}
+			kept = kept || (ok && it.rule.cmp.match(matchValues(children)))
+		}
+	}
+
+	// Drops and masks do not decide whether the value is part of the result, only what is written
+	if dropped || mask != nil {
+		present := kept || projected
+		if len(pending) > 0 || len(elements) > 0 {
+			// The value is walked without writing anything, so the rules below it still report
+			// their errors and can make it part of the result
+			var err error
+			if present, err = c.emit(src, pending, elements, kept, at.discarded(), projected); err != nil {
+				return false, err
+			}
+		} else if err := src.skip(); err != nil {
+			return false, err
+		}
+		if dropped || !present {
+			return present, nil
+		}
+
+		masked, err := mask.apply(src.value)
+		if err != nil {
+			return false, err
+		}
+		if err := c.out.value(at, masked); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	return c.emit(src, pending, elements, kept, at, projected)
+}
+
+// emit writes the value at the slot, descending into containers with the items still pending;
+// elements are the array element rules that apply to the value's elements
+func (c *streamCutter) emit(src *streamSource, items, elements []streamItem, kept bool, at slot, projected bool) (bool, error) {
+	kind, scalar, err := src.open()
+	if err != nil {
+		return false, err
+	}
+
+	if kind != '[' && len(elements) > 0 {
+		return false, errNotArray
+	}
+
+	if kind == 0 {
+		switch {
+		case kept:
+			return true, c.out.value(at, scalar)
+		case projected:
+			return true, c.out.value(at, map[string]interface{}{})
+		default:
+			return false, nil
+		}
+	}
+
+	// A bare name meeting an array is an error even when the array is empty
+	if kind == '[' {
+		for _, it := range items {
+			if seg := it.rest[0]; !it.lenient && seg.kind == nameSegment {
+				if _, _, err := arrayIndex(seg); err != nil {
+					return false, err
+				}
+			}
+		}
+	}
+
+	// FieldMask names need to know which member names the object has
+	var names *memberNames
+	if kind == '{' && pendsMaskField(items) {
+		names = newMemberNames(src)
+	}
+
+	f := &frame{at: at, array: kind == '['}
+	present := kept
+
+	length, err := src.each(func(key string, index int, child *streamSource) error {
+		names.add(key)
+		childItems, childKept, childProjected, err := advance(items, elements, f.array, key, index, src.length(), names, child)
+		if err == errMemberNames {
+			// A member matching a FieldMask name in another spelling waits for the names after it
+			if err = src.bufferRest(child, names); err == nil {
+				childItems, childKept, childProjected, err = advance(items, elements, f.array, key, index, src.length(), names, child)
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		p, err := c.cut(child, childItems, kept || childKept, slot{parent: f, key: key, index: index, discard: at.discard}, childProjected)
+		present = present || p
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case present:
+		c.out.close(f, length)
+		return true, nil
+	case projected:
+		return true, c.out.value(at, map[string]interface{}{})
+	default:
+		return false, nil
+	}
+}
+
+// advance works out which items apply to a child of a container: items whose next segment selects
+// the child move past it, descendant segments carry on unchanged, and the element rules are
+// evaluated against the child, which is buffered for that. length is -1 while it is unknown, and
+// names are the member names read so far of the object the child belongs to
+func advance(items, elements []streamItem, isArray bool, key string, index, length int, names *memberNames, child *streamSource) ([]streamItem, bool, bool, error) {
+	var out []streamItem
+	kept, projected := false, false
+
+	for _, it := range items {
+		seg := it.rest[0]
+
+		// Descendant segments match at every depth, and field names map over arrays
+		if seg.kind == descendantSegment || (isArray && (seg.kind == fieldSegment || seg.kind == maskFieldSegment)) {
+			out = addItem(out, it)
+			continue
+		}
+
+		ok, err := selectsChild(seg, isArray, key, index, length, names, child)
+		if err == ErrInvalidPath && it.lenient {
+			continue
+		}
+		if err != nil {
+			return nil, false, false, err
+		}
+		if ok {
+			out = addItem(out, streamItem{kind: it.kind, rule: it.rule, rest: it.rest[1:], lenient: it.lenient})
+		}
+	}
+
+	for _, it := range elements {
+		if err := child.materialize(); err != nil {
+			return nil, false, false, err
+		}
+		if !it.rule.cond.test(child.value, match{value: child.value}) {
+			continue
+		}
+
+		switch {
+		case it.kind == itemDropElements:
+			out = addItem(out, streamItem{kind: itemDrop, rule: it.rule})
+		case len(it.rule.fields) == 0:
+			kept = true
+		default:
+			projected = true
+			// A field path that does not fit the element is skipped rather than an error
+			for _, field := range it.rule.fields {
+				out = addItem(out, streamItem{kind: itemKeep, rule: it.rule, rest: field, lenient: true})
+			}
+		}
+	}
+
+	return out, kept, projected, nil
+}
+
+// selectsChild reports whether a single segment selects the child of an object at key, or of an
+// array at index; filters are evaluated against the child, which is buffered for that. It returns
+// errMemberNames when the answer depends on member names of the object that have not been read yet
+func selectsChild(seg segment, isArray bool, key string, index, length int, names *memberNames, child *streamSource) (bool, error) {
+	switch seg.kind {
+	case wildcardSegment:
+		return true, nil
+
+	case unionSegment:
+		for _, selector := range seg.selectors {
+			ok, err := selectsChild(selector, isArray, key, index, length, names, child)
+			if ok || err != nil {
+				return ok, err
+			}
+		}
+		return false, nil
+
+	case filterSegment:
+		if err := child.materialize(); err != nil {
+			return false, err
+		}
+		return seg.filter.test(&filterContext{root: child.value, current: child.value}), nil
+
+	case sliceSegment:
+		if !isArray {
+			return false, nil
+		}
+		b := seg.slice
+		if length < 0 {
+			// Without negative bounds or steps a slice does not depend on the array length
+			start := 0
+			if b.hasStart {
+				start = b.start
+			}
+			return b.step > 0 && index >= start && (!b.hasEnd || index < b.end) && (index-start)%b.step == 0, nil
+		}
+		for _, i := range b.indices(length) {
+			if i == index {
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case maskFieldSegment:
+		if isArray {
+			return false, nil
+		}
+		exact := names.has(seg.name)
+		if !exact && !names.complete && selectsMaskField(seg.name, key, false) {
+			return false, errMemberNames
+		}
+		return selectsMaskField(seg.name, key, exact), nil
+	}
+
+	if !isArray {
+		return seg.kind != indexSegment && key == seg.name, nil
+	}
+
+	want, ok, err := arrayIndex(seg)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	if want < 0 {
+		if length < 0 {
+			return false, nil
+		}
+		want += length
+	}
+	return want == index, nil
+}
+
+// needsLength reports whether a segment can only select array elements once the length of the array
+// is known: negative indices, and slices with negative bounds or steps, count from the end
+func needsLength(seg segment) bool {
+	switch seg.kind {
+	case indexSegment:
+		return seg.index < 0
+	case nameSegment:
+		return !seg.quoted && isNumeric(seg.name) && seg.name[0] == '-'
+	case sliceSegment:
+		return seg.slice.step < 0 || (seg.slice.hasStart && seg.slice.start < 0) || (seg.slice.hasEnd && seg.slice.end < 0)
+	case unionSegment:
+		for _, selector := range seg.selectors {
+			if needsLength(selector) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// errMemberNames reports that a member can only be selected once the names of all members of its object are known
+var errMemberNames = errors.New("member names of the object are not known yet")
+
+// memberNames are the member names of an object being read, as far as they are known
+type memberNames struct {
+	seen     map[string]bool
+	complete bool // every member name of the object is known
+}
+
+// newMemberNames starts tracking the member names of an opened object, which are all known if it is buffered
+func newMemberNames(src *streamSource) *memberNames {
+	names := &memberNames{seen: make(map[string]bool), complete: src.buffered}
+	if obj, ok := src.value.(map[string]interface{}); ok && src.buffered {
+		for name := range obj {
+			names.seen[name] = true
+		}
+	}
+	return names
+}
+
+// add records a member name; names that are not tracked are ignored
+func (n *memberNames) add(name string) {
+	if n != nil {
+		n.seen[name] = true
+	}
+}
+
+// has reports whether the object has a member with the name
+func (n *memberNames) has(name string) bool {
+	return n != nil && n.seen[name]
+}
+
+// pendsMaskField reports whether the next segment of an item is a FieldMask field name
+func pendsMaskField(items []streamItem) bool {
+	for _, it := range items {
+		if it.rest[0].kind == maskFieldSegment {
+			return true
+		}
+	}
+	return false
+}
+
+// expandDescendants adds, for every item whose next segment is a descendant segment, the item
+// matching the segments after it at the same value
+func expandDescendants(items []streamItem) []streamItem {
+	var out []streamItem
+	for _, it := range items {
+		out = addItem(out, it)
+		for len(it.rest) > 0 && it.rest[0].kind == descendantSegment {
+			it = streamItem{kind: it.kind, rule: it.rule, rest: it.rest[1:], lenient: true}
+			out = addItem(out, it)
+		}
+	}
+	return out
+}
+
+// addItem appends it unless an item in the same state is already present, so that paths such as
+// **.** do not multiply the items at every level
+func addItem(items []streamItem, it streamItem) []streamItem {
+	for i, other := range items {
+		if other.kind == it.kind && other.rule == it.rule && len(other.rest) == len(it.rest) &&
+			(len(it.rest) == 0 || &other.rest[0] == &it.rest[0]) {
+			items[i].lenient = other.lenient && it.lenient
+			return items
+		}
+	}
+	return append(items, it)
+}
+
+// segmentsReferenceRoot reports whether a filter in the segments refers to the document root with $
+func segmentsReferenceRoot(segments []segment) bool {
+	for _, seg := range segments {
+		if seg.kind == filterSegment && exprReferencesRoot(seg.filter) {
+			return true
+		}
+		if seg.kind == unionSegment && segmentsReferenceRoot(seg.selectors) {
+			return true
+		}
+	}
+	return false
+}
+
+// conditionReferencesRoot reports whether a path in the condition tree has a filter referring to the document root
+func conditionReferencesRoot(c *condition) bool {
+	if c == nil {
+		return false
+	}
+	for _, child := range append(append([]*condition{c.not}, c.all...), c.any...) {
+		if conditionReferencesRoot(child) {
+			return true
+		}
+	}
+	return segmentsReferenceRoot(c.segments)
+}
+
+// exprReferencesRoot reports whether a filter expression contains an absolute query
+func exprReferencesRoot(e interface{}) bool {
+	switch x := e.(type) {
+	case *orExpr:
+		return exprReferencesRoot(x.left) || exprReferencesRoot(x.right)
+	case *andExpr:
+		return exprReferencesRoot(x.left) || exprReferencesRoot(x.right)
+	case *notExpr:
+		return exprReferencesRoot(x.expr)
+	case *existsExpr:
+		return exprReferencesRoot(x.query)
+	case *comparisonExpr:
+		return exprReferencesRoot(x.left) || exprReferencesRoot(x.right)
+	case *functionExpr:
+		for _, arg := range x.args {
+			if exprReferencesRoot(arg) {
+				return true
+			}
+		}
+		return false
+	case *queryExpr:
+		return x.absolute || segmentsReferenceRoot(x.segments)
+	default:
+		return false
+	}
+}
+
+// streamSource is a value of the document being cut: either the next value in the token stream,
+// or a value that has been buffered because a rule has to look at it as a whole
+type streamSource struct {
+	dec      *json.Decoder
+	kind     byte // '{' or '[' once a container in the token stream has been opened
+	buffered bool
+	value    interface{}
+	order    keyOrder
+
+	rest      map[string]interface{} // the buffered members after the current one, see bufferRest
+	restOrder keyOrder
+}
+
+// materialize buffers the value if it is still in the token stream
+func (s *streamSource) materialize() error {
+	if s.buffered {
+		return nil
+	}
+
+	order := make(keyOrder)
+	value, err := decodeValue(s.dec, order)
+	if err != nil {
+		return ErrInvalidJSON
+	}
+
+	s.value, s.order, s.buffered = value, order, true
+	return nil
+}
+
+// skip consumes the value without looking at it
+func (s *streamSource) skip() error {
+	if s.buffered {
+		return nil
+	}
+
+	depth := 0
+	for {
+		tok, err := s.dec.Token()
+		if err != nil {
+			return ErrInvalidJSON
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// open starts reading the value: kind is '{' or '[' for a container, whose members are then read
+// with each, or 0 for a scalar, which is returned
+func (s *streamSource) open() (byte, interface{}, error) {
+	if s.buffered {
+		switch s.value.(type) {
+		case map[string]interface{}:
+			return '{', nil, nil
+		case []interface{}:
+			return '[', nil, nil
+		default:
+			return 0, s.value, nil
+		}
+	}
+
+	tok, err := s.dec.Token()
+	if err != nil {
+		return 0, nil, ErrInvalidJSON
+	}
+	switch tok {
+	case json.Delim('{'):
+		s.kind = '{'
+		return '{', nil, nil
+	case json.Delim('['):
+		s.kind = '['
+		return '[', nil, nil
+	case json.Delim('}'), json.Delim(']'):
+		return 0, nil, ErrInvalidJSON
+	default:
+		return 0, tok, nil
+	}
+}
+
+// bufferRest buffers the current member of an object being read from the token stream and the
+// members after it, which each then goes on with, and adds their names to names
+func (s *streamSource) bufferRest(current *streamSource, names *memberNames) error {
+	if err := current.materialize(); err != nil {
+		return err
+	}
+
+	order := make(keyOrder)
+	rest, err := decodeMembers(s.dec, order)
+	if err != nil {
+		return ErrInvalidJSON
+	}
+
+	s.rest, s.restOrder = rest, order
+	for name := range rest {
+		names.add(name)
+	}
+	names.complete = true
+	return nil
+}
+
+// length returns the length of a buffered array, or -1 if it is not known yet
+func (s *streamSource) length() int {
+	if v, ok := s.value.([]interface{}); ok && s.buffered {
+		return len(v)
+	}
+	return -1
+}
+
+// each calls fn for every member of the opened container, in source order; fn must consume the
+// child. It returns the number of members
+func (s *streamSource) each(fn func(key string, index int, child *streamSource) error) (int, error) {
+	if s.buffered {
+		switch v := s.value.(type) {
+		case map[string]interface{}:
+			keys := s.order.keys(v)
+			if keys == nil {
+				for k := range v {
+					keys = append(keys, k)
+				}
+				sort.Strings(keys)
+			}
+			for i, k := range keys {
+				child := &streamSource{buffered: true, value: v[k], order: s.order}
+				if err := fn(k, i, child); err != nil {
+					return 0, err
+				}
+			}
+			return len(keys), nil
+
+		case []interface{}:
+			for i, element := range v {
+				child := &streamSource{buffered: true, value: element, order: s.order}
+				if err := fn("", i, child); err != nil {
+					return 0, err
+				}
+			}
+			return len(v), nil
+		}
+		return 0, nil
+	}
+
+	n := 0
+	for s.dec.More() {
+		var key string
+		if s.kind == '{' {
+			tok, err := s.dec.Token()
+			if err != nil {
+				return 0, ErrInvalidJSON
+			}
+			key = tok.(string)
+		}
+		if err := fn(key, n, &streamSource{dec: s.dec}); err != nil {
+			return 0, err
+		}
+		n++
+
+		// The rest of the object, its closing brace included, has been read into memory
+		if s.rest != nil {
+			for _, k := range s.restOrder.keys(s.rest) {
+				if err := fn(k, n, &streamSource{buffered: true, value: s.rest[k], order: s.restOrder}); err != nil {
+					return 0, err
+				}
+				n++
+			}
+			return n, nil
+		}
+	}
+
+	if _, err := s.dec.Token(); err != nil {
+		return 0, ErrInvalidJSON
+	}
+	return n, nil
+}
+
+// slot is where a value goes in the output: a member of an object frame, an element of an array
+// frame, or the document root when parent is nil
+type slot struct {
+	parent  *frame
+	key     string
+	index   int
+	discard bool // the value is only being looked at and nothing is written
+}
+
+// discarded returns the slot with writing turned off
+func (at slot) discarded() slot {
+	at.discard = true
+	return at
+}
+
+// frame is a container in the output; it is only opened once something is written into it
+type frame struct {
+	at     slot
+	array  bool
+	opened bool
+	count  int // members written, placeholders included
+}
+
+// streamWriter writes the result of CutReader
+type streamWriter struct {
+	w           *bufio.Writer
+	preserve    bool // array elements keep their source positions
+	placeholder []byte
+	started     bool // the root value has been started
+}
+
+// begin writes what precedes a value at the slot: the opening of every enclosing container not
+// yet open, a separator, and the member name or the placeholders of the positions skipped before it
+func (s *streamWriter) begin(at slot) {
+	p := at.parent
+	if p == nil {
+		s.started = true
+		return
+	}
+
+	s.open(p)
+	if p.array && s.preserve {
+		for p.count < at.index {
+			s.separate(p)
+			s.w.Write(s.placeholder)
+		}
+	}
+	s.separate(p)
+
+	if !p.array {
+		name, _ := json.Marshal(at.key)
+		s.w.Write(name)
+		s.w.WriteByte(':')
+	}
+}
+
+// separate writes the comma before every member of a container but the first
+func (s *streamWriter) separate(f *frame) {
+	if f.count > 0 {
+		s.w.WriteByte(',')
+	}
+	f.count++
+}
+
+// open writes the opening bracket of a container and everything that has to precede it
+func (s *streamWriter) open(f *frame) {
+	if f.opened || f.at.discard {
+		return
+	}
+	s.begin(f.at)
+	if f.array {
+		s.w.WriteByte('[')
+	} else {
+		s.w.WriteByte('{')
+	}
+	f.opened = true
+}
+
+// close ends a container, opening it first if nothing was written into it; length is the
+// number of elements of the source array, up to which kept positions are padded
+func (s *streamWriter) close(f *frame, length int) {
+	if f.at.discard {
+		return
+	}
+	s.open(f)
+
+	if !f.array {
+		s.w.WriteByte('}')
+		return
+	}
+	if s.preserve {
+		for f.count < length {
+			s.separate(f)
+			s.w.Write(s.placeholder)
+		}
+	}
+	s.w.WriteByte(']')
+}
+
+// value writes a complete JSON value at the slot
+func (s *streamWriter) value(at slot, v interface{}) error {
+	if at.discard {
+		return nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	s.begin(at)
+	s.w.Write(data)
+	return nil
+}
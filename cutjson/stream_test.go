@@ -0,0 +1,144 @@
+package cutjson
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCutReader(t *testing.T) {
+	jsonData := `{
+		"zeta": 1,
+		"user": {"name": "John Doe", "email": "john@example.com", "tags": ["a", "b"], "displayName": "John", "display_name": "J. Doe"},
+		"orders": [
+			{"id": 9007199254740993, "status": "paid", "items": [{"sku": "A", "qty": 1}, {"sku": "B", "qty": 2}], "card": "4111111111111111"},
+			{"id": 2, "status": "cancelled", "items": [], "card": "5500"},
+			{"id": 3, "status": "paid", "items": [{"sku": "C", "qty": 3}]}
+		],
+		"empty": {}
+	}`
+
+	stream := func(rules []Rule, opts Options) (string, error) {
+		var out bytes.Buffer
+		err := CutReader(strings.NewReader(jsonData), &out, rules, opts)
+		return out.String(), err
+	}
+
+	Convey("测试流式裁剪", t, func() {
+		Convey("按源文档顺序输出保留的内容", func() {
+			result, err := stream([]Rule{NewKeepPathRule("user.tags"), NewKeepPathRule("zeta"), NewKeepPathRule("orders.1.id")}, Options{})
+			So(err, ShouldBeNil)
+			So(result, ShouldEqual, `{"zeta":1,"user":{"tags":["a","b"]},"orders":[{"id":2}]}`)
+		})
+
+		Convey("逐个元素判断数组元素规则", func() {
+			rule := NewKeepArrayElementsIfChildValueMatchesRule("orders", "status", "paid")
+			rule.Fields = []string{"id", "items.*.sku"}
+			result, err := stream([]Rule{rule}, Options{})
+			So(err, ShouldBeNil)
+			So(result, ShouldEqual, `{"orders":[{"id":9007199254740993,"items":[{"sku":"A"},{"sku":"B"}]},{"id":3,"items":[{"sku":"C"}]}]}`)
+
+			result, err = stream([]Rule{
+				NewDropArrayElementsIfChildValueMatchesRule("orders", "status", "cancelled"),
+				NewDropPathRule("user"),
+				NewMaskPathRule("orders.*.card", Mask{Mode: MaskKeepLast, KeepLast: 4}),
+			}, Options{})
+			So(err, ShouldBeNil)
+			So(result, ShouldEqual, `{"zeta":1,"orders":[{"id":9007199254740993,"status":"paid","items":[{"sku":"A","qty":1},{"sku":"B","qty":2}],"card":"************1111"},{"id":3,"status":"paid","items":[{"sku":"C","qty":3}]}],"empty":{}}`)
+		})
+
+		Convey("保持数组元素位置", func() {
+			result, err := stream([]Rule{NewKeepPathRule("orders.1.status")}, Options{ArrayMode: PreserveArrayPositions, Placeholder: 0})
+			So(err, ShouldBeNil)
+			So(result, ShouldEqual, `{"orders":[0,{"status":"cancelled"},0]}`)
+		})
+
+		Convey("结果与CutWithOptions保持键顺序时一致", func() {
+			ruleSets := [][]Rule{
+				{NewKeepPathRule("orders.-1.id")},
+				{NewKeepPathRule("orders[::2].status")},
+				{NewKeepPathRule("**.sku")},
+				{NewKeepPathRule("$..items[?@.qty > 1]")},
+				{NewKeepPathRule("$.orders[?@.id == $.zeta]")},
+				{NewKeepPathRule("missing")},
+				{NewKeepPathRule("user.name"), NewDropPathRule("user.name")},
+				{NewKeepPathRule("orders.0.items.0"), NewMaskPathRule("orders.0.items", Mask{Mode: MaskHash})},
+				{NewKeepParentIfValueMatchesRule("orders.*.status", "paid")},
+				{NewKeepParentIfValueMatchesRule("zeta", 1)},
+				{NewKeepParentIfValueMatchesRule("**.sku", "C")},
+				{NewKeepParentIfValueMatchesRule("..status", "paid")},
+				{NewKeepParentIfValueMatchesRule("*", 1)},
+				{NewKeepParentIfValueComparesRule("*", OpExists, nil)},
+				{NewKeepParentIfValueComparesRule("orders.*.card", OpNotExists, nil)},
+				{NewKeepParentIfConditionRule("orders.*", All(Where("status", OpEquals, "paid"), Where("items.*.qty", OpGreaterThan, 2)))},
+				{NewKeepArrayElementsIfChildValueMatchesRule("orders", "status", "paid"), NewKeepPathRule("orders.1.id")},
+				{NewDropPathRule("$")},
+				{{Type: KeepPath, Path: "user.display_name", PathSyntax: PathFieldMask}, {Type: KeepPath, Path: "orders.items.sku", PathSyntax: PathFieldMask}},
+				{{Type: KeepPath, Path: "user.displayName", PathSyntax: PathFieldMask}, {Type: DropPath, Path: "user.Email", PathSyntax: PathFieldMask}},
+			}
+			for _, opts := range []Options{{}, {ArrayMode: PreserveArrayPositions}, {KeepAll: true}, {Conflict: ConflictIntersection}} {
+				for _, rules := range ruleSets {
+					result, err := stream(rules, opts)
+					So(err, ShouldBeNil)
+
+					opts.PreserveKeyOrder = true
+					expected, err := CutWithOptions([]byte(jsonData), rules, opts)
+					So(err, ShouldBeNil)
+					So(result, ShouldEqual, string(expected))
+				}
+			}
+		})
+
+		Convey("FieldMask名称精确匹配的成员优先，其他写法的成员之后的内容才被缓冲", func() {
+			mask := func(path string) []Rule {
+				return []Rule{{Type: KeepPath, Path: path, PathSyntax: PathFieldMask}}
+			}
+			for _, input := range []string{`{"a":0,"displayName":1,"b":2,"display_name":3}`, `{"display_name":3,"b":2,"displayName":1}`} {
+				var out bytes.Buffer
+				So(CutReader(strings.NewReader(input), &out, mask("display_name"), Options{}), ShouldBeNil)
+				So(out.String(), ShouldEqual, `{"display_name":3}`)
+			}
+
+			var out bytes.Buffer
+			rules := append(mask("displayName"), Rule{Type: DropPath, Path: "B", PathSyntax: PathFieldMask})
+			So(CutReader(strings.NewReader(`{"a":0,"display_name":3,"b":2,"c":{"d":4}}`), &out, rules, Options{KeepAll: true}), ShouldBeNil)
+			So(out.String(), ShouldEqual, `{"a":0,"display_name":3,"c":{"d":4}}`)
+		})
+
+		Convey("被移除或替换的值中的规则与CutWithOptions报告相同的错误", func() {
+			data := `{"a":1,"b":[1]}`
+			cases := []struct {
+				rules []Rule
+				opts  Options
+			}{
+				{[]Rule{NewDropArrayElementsIfChildValueMatchesRule("a", "x", 1), NewMaskPathRule("*", Mask{})}, Options{}},
+				{[]Rule{NewKeepPathRule("*.a"), NewDropPathRule("*")}, Options{KeepAll: true}},
+				{[]Rule{NewKeepPathRule("b.x"), NewDropPathRule("b")}, Options{}},
+				{[]Rule{NewDropPathRule("b.x"), NewMaskPathRule("b", Mask{})}, Options{}},
+				{[]Rule{NewKeepArrayElementsIfChildValueMatchesRule("a", "x", 1), NewDropPathRule("$")}, Options{}},
+			}
+			for _, c := range cases {
+				_, expected := CutWithOptions([]byte(data), c.rules, c.opts)
+				So(expected, ShouldNotBeNil)
+
+				err := CutReader(strings.NewReader(data), &bytes.Buffer{}, c.rules, c.opts)
+				So(err, ShouldEqual, expected)
+			}
+		})
+
+		Convey("错误处理", func() {
+			_, err := stream([]Rule{NewKeepArrayElementsIfChildValueMatchesRule("user", "name", "x")}, Options{})
+			So(err, ShouldEqual, errNotArray)
+
+			_, err = stream([]Rule{NewKeepPathRule("orders.name")}, Options{})
+			So(err, ShouldEqual, ErrInvalidPath)
+
+			for _, input := range []string{``, `{"a":1} x`, `{"a":`, `[1,2]]`} {
+				err := CutReader(strings.NewReader(input), &bytes.Buffer{}, []Rule{NewKeepPathRule("0")}, Options{})
+				So(err, ShouldEqual, ErrInvalidJSON)
+			}
+		})
+	})
+}